@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/googlesql"
+	"github.com/dtan4/bqc/internal/history"
+	"github.com/dtan4/bqc/internal/renderer"
+)
+
+var renderers = map[string]renderer.Renderer{
+	"table":    &renderer.TableRenderer{},
+	"markdown": &renderer.MarkdownRenderer{},
+	"tsv":      &renderer.TSVRenderer{},
+	"csv":      &renderer.CSVRenderer{},
+	"json":     &renderer.JSONRenderer{},
+	"jsonl":    &renderer.JSONLinesRenderer{},
+}
+
+func rendererFromFormat(format string) (renderer.Renderer, error) {
+	rdr, ok := renderers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+
+	return rdr, nil
+}
+
+// loadBatchQuery returns the query to run non-interactively. The query is taken
+// from -e, -f or piped stdin in this order. The second return value is false if
+// no query is given, i.e. bqc should launch the TUI.
+func loadBatchQuery(execQuery, queryFile string, stdin *os.File) (string, bool, error) {
+	if execQuery != "" && queryFile != "" {
+		return "", false, errors.New("-e and -f cannot be specified at the same time")
+	}
+
+	var query string
+
+	switch {
+	case execQuery != "":
+		query = execQuery
+
+	case queryFile != "":
+		b, err := os.ReadFile(queryFile)
+		if err != nil {
+			return "", false, fmt.Errorf("read query file %s: %w", queryFile, err)
+		}
+
+		query = string(b)
+
+	case isPiped(stdin):
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", false, fmt.Errorf("read query from stdin: %w", err)
+		}
+
+		query = string(b)
+
+	default:
+		return "", false, nil
+	}
+
+	if strings.TrimSpace(query) == "" {
+		return "", false, errors.New("query is empty")
+	}
+
+	return query, true, nil
+}
+
+func isPiped(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice == 0
+}
+
+// parameterFlags collects query parameters given by -param as name:TYPE:value.
+// The type may be omitted as name::value for STRING.
+type parameterFlags []bigquery.Parameter
+
+var _ flag.Value = (*parameterFlags)(nil)
+
+func (p *parameterFlags) String() string {
+	vs := make([]string, 0, len(*p))
+	for _, param := range *p {
+		vs = append(vs, fmt.Sprintf("%s:%s:%s", param.Name, param.Type, param.Value))
+	}
+
+	return strings.Join(vs, ",")
+}
+
+func (p *parameterFlags) Set(s string) error {
+	name, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("invalid parameter %q, want name:TYPE:value", s)
+	}
+
+	typ, value, ok := strings.Cut(rest, ":")
+	if !ok {
+		return fmt.Errorf("invalid parameter %q, want name:TYPE:value", s)
+	}
+
+	name = strings.TrimPrefix(name, "@")
+	if name == "" {
+		return fmt.Errorf("invalid parameter %q, name is empty", s)
+	}
+
+	typ = strings.ToUpper(typ)
+	if typ == "" {
+		typ = "STRING"
+	}
+
+	if !slices.Contains(bigquery.ParameterTypes, typ) {
+		return fmt.Errorf("invalid type %q of parameter %s, want one of %s", typ, name, strings.Join(bigquery.ParameterTypes, ", "))
+	}
+
+	*p = append(*p, bigquery.Parameter{Name: name, Type: typ, Value: value})
+
+	return nil
+}
+
+// checkParameters makes sure that every parameter referenced in query is
+// given, rather than letting BigQuery fail with the query half-typed.
+func checkParameters(query string, params []bigquery.Parameter) error {
+	for _, name := range googlesql.Parameters(query) {
+		given := slices.ContainsFunc(params, func(p bigquery.Parameter) bool {
+			return strings.EqualFold(p.Name, name)
+		})
+
+		if !given {
+			return fmt.Errorf("query parameter @%s is not given, use -param %s:TYPE:value", name, name)
+		}
+	}
+
+	return nil
+}
+
+func runBatch(
+	ctx context.Context,
+	client bigquery.QueryBackend,
+	hs history.Storage,
+	rdr renderer.Renderer,
+	query string,
+	params []bigquery.Parameter,
+	w io.Writer,
+) error {
+	if err := checkParameters(query, params); err != nil {
+		return err
+	}
+
+	r, err := client.RunQuery(ctx, query, params...)
+	if err != nil {
+		return fmt.Errorf("run query: %w", err)
+	}
+
+	out, err := rdr.Render(r)
+	if err != nil {
+		return fmt.Errorf("render result: %w", err)
+	}
+
+	if _, err := io.WriteString(w, out); err != nil {
+		return fmt.Errorf("write result: %w", err)
+	}
+
+	// the result is written even if it cannot be recorded
+	if err := hs.Append(r); err != nil {
+		return fmt.Errorf("append query to history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/bigquery/bigquerytest"
+	"github.com/dtan4/bqc/internal/history"
+)
+
+func TestRunBatch(t *testing.T) {
+	t.Parallel()
+
+	hs, err := history.NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hs.Close()
+	})
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1 as foo", &bigquery.Result{
+		Keys: []string{"foo"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(1)},
+		},
+		EndTime: time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+	})
+
+	rdr, err := rendererFromFormat("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+
+	if err := runBatch(context.Background(), fake, hs, rdr, "select 1 as foo", nil, &b); err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff("foo\n1\n", b.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	rs, err := hs.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs) != 1 || rs[0].Query != "select 1 as foo" {
+		t.Errorf("want the query recorded in history, got: %#v", rs)
+	}
+}
+
+func TestRunBatch_valueTypes(t *testing.T) {
+	t.Parallel()
+
+	query := "select n, b, ts, x"
+
+	fake := bigquerytest.NewFakeClient().AddResult(query, &bigquery.Result{
+		Keys: []string{"n", "b", "ts", "x"},
+		Schema: []*bigquery.Field{
+			{Name: "n", Type: "NUMERIC", Mode: bigquery.ModeNullable},
+			{Name: "b", Type: "BYTES", Mode: bigquery.ModeNullable},
+			{Name: "ts", Type: "TIMESTAMP", Mode: bigquery.ModeNullable},
+			{Name: "x", Type: "STRING", Mode: bigquery.ModeNullable},
+		},
+		Rows: [][]bigqueryapi.Value{
+			{
+				big.NewRat(12345, 100),
+				[]byte("bqc"),
+				time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+				nil,
+			},
+		},
+	})
+
+	testcases := map[string]string{
+		"tsv": "n\tb\tts\tx\n123.45\tYnFj\t2023-05-03 12:34:56 +0000 UTC\t<nil>\n",
+		"csv": "n,b,ts,x\n123.45,YnFj,2023-05-03 12:34:56 +0000 UTC,\n",
+	}
+
+	for format, want := range testcases {
+		format, want := format, want
+
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			hs, err := history.NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				hs.Close()
+			})
+
+			rdr, err := rendererFromFormat(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+
+			if err := runBatch(context.Background(), fake, hs, rdr, query, nil, &b); err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			if diff := cmp.Diff(want, b.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRendererFromFormat_unknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := rendererFromFormat("xml"); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestRunBatch_error(t *testing.T) {
+	t.Parallel()
+
+	hs, err := history.NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hs.Close()
+	})
+
+	fake := bigquerytest.NewFakeClient().AddError("select foo", errors.New("Unrecognized name: foo"))
+
+	rdr, err := rendererFromFormat("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+
+	err = runBatch(context.Background(), fake, hs, rdr, "select foo", nil, &b)
+	if err == nil || !strings.Contains(err.Error(), "run query: Unrecognized name: foo") {
+		t.Errorf("want query error, got: %v", err)
+	}
+
+	if b.Len() != 0 {
+		t.Errorf("want no output, got: %q", b.String())
+	}
+}
+
+func TestRunBatch_parameters(t *testing.T) {
+	t.Parallel()
+
+	hs, err := history.NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hs.Close()
+	})
+
+	query := "select @day as day"
+	params := []bigquery.Parameter{
+		{Name: "day", Type: "DATE", Value: "2024-01-02"},
+	}
+
+	fake := bigquerytest.NewFakeClient().AddResult(query, &bigquery.Result{
+		Keys: []string{"day"},
+		Rows: [][]bigqueryapi.Value{
+			{"2024-01-02"},
+		},
+	})
+
+	rdr, err := rendererFromFormat("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+
+	if err := runBatch(context.Background(), fake, hs, rdr, query, params, &b); err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff([][]bigquery.Parameter{params}, fake.Parameters()); diff != "" {
+		t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunBatch_missingParameter(t *testing.T) {
+	t.Parallel()
+
+	hs, err := history.NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hs.Close()
+	})
+
+	fake := bigquerytest.NewFakeClient()
+
+	rdr, err := rendererFromFormat("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+
+	params := []bigquery.Parameter{
+		{Name: "Day", Type: "DATE", Value: "2024-01-02"},
+	}
+
+	err = runBatch(context.Background(), fake, hs, rdr, "select @day, @limit", params, &b)
+	if err == nil || !strings.Contains(err.Error(), "query parameter @limit is not given") {
+		t.Errorf("want missing parameter error, got: %v", err)
+	}
+
+	if len(fake.Queries()) != 0 {
+		t.Errorf("want no query run, got: %q", fake.Queries())
+	}
+}
+
+func TestParameterFlags(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		args    []string
+		want    parameterFlags
+		wantErr bool
+	}{
+		"typed": {
+			args: []string{"day:date:2024-01-02", "@n:INT64:3"},
+			want: parameterFlags{
+				{Name: "day", Type: "DATE", Value: "2024-01-02"},
+				{Name: "n", Type: "INT64", Value: "3"},
+			},
+		},
+		"string by default": {
+			args: []string{"name::a:b"},
+			want: parameterFlags{
+				{Name: "name", Type: "STRING", Value: "a:b"},
+			},
+		},
+		"no type": {
+			args:    []string{"day"},
+			wantErr: true,
+		},
+		"no value": {
+			args:    []string{"day:DATE"},
+			wantErr: true,
+		},
+		"no name": {
+			args:    []string{":DATE:2024-01-02"},
+			wantErr: true,
+		},
+		"unknown type": {
+			args:    []string{"day:DAY:2024-01-02"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got parameterFlags
+
+			var err error
+			for _, arg := range tc.args {
+				if err = got.Set(arg); err != nil {
+					break
+				}
+			}
+
+			if tc.wantErr {
+				if err == nil {
+					t.Error("want error, got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadBatchQuery(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	queryFile := filepath.Join(dir, "query.sql")
+	if err := os.WriteFile(queryFile, []byte("select 2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	emptyFile := filepath.Join(dir, "empty.sql")
+	if err := os.WriteFile(emptyFile, []byte(" \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		execQuery string
+		queryFile string
+		stdin     string
+		want      string
+		wantBatch bool
+		wantErr   bool
+	}{
+		"-e": {
+			execQuery: "select 1",
+			stdin:     "select 3",
+			want:      "select 1",
+			wantBatch: true,
+		},
+		"-f": {
+			queryFile: queryFile,
+			stdin:     "select 3",
+			want:      "select 2",
+			wantBatch: true,
+		},
+		"stdin": {
+			stdin:     "select 3",
+			want:      "select 3",
+			wantBatch: true,
+		},
+		"-e and -f": {
+			execQuery: "select 1",
+			queryFile: queryFile,
+			wantErr:   true,
+		},
+		"missing file": {
+			queryFile: filepath.Join(dir, "missing.sql"),
+			wantErr:   true,
+		},
+		"empty query": {
+			queryFile: emptyFile,
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stdin := pipe(t, tc.stdin)
+
+			got, batch, err := loadBatchQuery(tc.execQuery, tc.queryFile, stdin)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error, got query: %q", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tc.want || batch != tc.wantBatch {
+				t.Errorf("want: (%q, %t), got: (%q, %t)", tc.want, tc.wantBatch, got, batch)
+			}
+		})
+	}
+}
+
+func TestLoadBatchQuery_terminal(t *testing.T) {
+	t.Parallel()
+
+	// the null device is a character device like a terminal
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		f.Close()
+	})
+
+	got, batch, err := loadBatchQuery("", "", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batch || got != "" {
+		t.Errorf("want the TUI launched, got: (%q, %t)", got, batch)
+	}
+}
+
+func TestIsPiped(t *testing.T) {
+	t.Parallel()
+
+	if !isPiped(pipe(t, "select 1")) {
+		t.Error("want a pipe treated as piped stdin")
+	}
+
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		f.Close()
+	})
+
+	if isPiped(f) {
+		t.Error("want a character device not treated as piped stdin")
+	}
+}
+
+// pipe returns the read end of a pipe which the text is written into.
+func pipe(t *testing.T, text string) *os.File {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+	})
+
+	go func() {
+		defer w.Close()
+
+		io.WriteString(w, text)
+	}()
+
+	return r
+}
@@ -0,0 +1,147 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	bqv2 "google.golang.org/api/bigquery/v2"
+)
+
+const testProjectID = "test-project"
+
+// fakeAPI serves the BigQuery REST API for tests of Client. Each test
+// registers handlers of the requests it expects, and any other request fails
+// the test.
+type fakeAPI struct {
+	t   *testing.T
+	mux *http.ServeMux
+	srv *httptest.Server
+
+	mu sync.Mutex
+	// requests are "METHOD path" of requests received so far
+	requests []string
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	t.Helper()
+
+	a := &fakeAPI{
+		t:   t,
+		mux: http.NewServeMux(),
+	}
+
+	a.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		writeJSON(w, http.StatusNotFound, errorResponse(http.StatusNotFound, "not found"))
+	})
+
+	a.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		a.requests = append(a.requests, r.Method+" "+r.URL.Path)
+		a.mu.Unlock()
+
+		a.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(a.srv.Close)
+
+	return a
+}
+
+// client returns a Client talking to the fake API.
+func (a *fakeAPI) client(opts ...ClientOption) *Client {
+	a.t.Helper()
+
+	c, err := NewClient(context.Background(), testProjectID, append([]ClientOption{WithEndpoint(a.srv.URL)}, opts...)...)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	a.t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+// handle responds to requests matching pattern, e.g. "GET /projects/{p}/jobs",
+// with the status code and the JSON returned by f.
+func (a *fakeAPI) handle(pattern string, f func(r *http.Request) (int, any)) {
+	a.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		code, v := f(r)
+		writeJSON(w, code, v)
+	})
+}
+
+// handleInsert accepts query jobs, which are reported as running.
+func (a *fakeAPI) handleInsert() {
+	a.handle("POST /projects/{p}/jobs", func(r *http.Request) (int, any) {
+		var j bqv2.Job
+		if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+			a.t.Errorf("decode job: %s", err)
+		}
+
+		j.Status = &bqv2.JobStatus{State: "RUNNING"}
+		j.Statistics = &bqv2.JobStatistics{CreationTime: 1700000000000}
+
+		return http.StatusOK, &j
+	})
+}
+
+// doneJob returns a job which has finished with stats.
+func doneJob(id string, stats *bqv2.JobStatistics) *bqv2.Job {
+	return &bqv2.Job{
+		JobReference: &bqv2.JobReference{ProjectId: testProjectID, JobId: id},
+		Status:       &bqv2.JobStatus{State: "DONE"},
+		Statistics:   stats,
+	}
+}
+
+// queryResults returns the complete result of a query job. BigQuery returns
+// rows only if maxResults is not 0, but they are always returned here since
+// the client ignores rows while waiting for the job.
+func queryResults(schema *bqv2.TableSchema, rows ...*bqv2.TableRow) *bqv2.GetQueryResultsResponse {
+	return &bqv2.GetQueryResultsResponse{
+		JobComplete: true,
+		Schema:      schema,
+		Rows:        rows,
+		TotalRows:   uint64(len(rows)),
+	}
+}
+
+// row returns a table row of the given values.
+func row(values ...any) *bqv2.TableRow {
+	r := &bqv2.TableRow{}
+
+	for _, v := range values {
+		r.F = append(r.F, &bqv2.TableCell{V: v})
+	}
+
+	return r
+}
+
+// received returns "METHOD path" of requests received so far.
+func (a *fakeAPI) received() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]string{}, a.requests...)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func errorResponse(code int, message string) any {
+	return map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+			"errors": []map[string]any{
+				{"reason": "invalid", "message": message},
+			},
+		},
+	}
+}
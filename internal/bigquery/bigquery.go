@@ -2,106 +2,502 @@ package bigquery
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/bigquery"
-	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 )
 
+// QueryBackend runs queries. It is implemented by Client, and by
+// bigquerytest.FakeClient for tests.
+type QueryBackend interface {
+	RunQuery(ctx context.Context, query string, params ...Parameter) (*Result, error)
+	StreamQuery(ctx context.Context, query string, pageSize int, params ...Parameter) (*Result, *RowStream, error)
+	DryRunQuery(ctx context.Context, query string, params ...Parameter) (*Result, error)
+	SaveQuery(ctx context.Context, query string, dst *Destination, params ...Parameter) (*Result, error)
+	// ScriptChildren reads results of the statements run by the script,
+	// which are not read with the result of the script itself.
+	ScriptChildren(ctx context.Context, script *Result, pageSize int) ([]*Result, error)
+}
+
+// Backend is everything bqc needs from BigQuery.
+type Backend interface {
+	QueryBackend
+	CatalogBackend
+	SessionBackend
+}
+
 type Client struct {
-	api *bigquery.Client
+	api            *bigquery.Client
+	maxBytesBilled int64
+	sessionEnabled bool
+	jobConfig      jobConfig
+
+	// session is the session queries run in. nil until the first query
+	// creates it.
+	session *Session
+	// sessionGen is incremented whenever the session is ended, so that jobs
+	// started before don't bring it back.
+	sessionGen uint64
+	mu         sync.Mutex
+}
+
+var _ Backend = (*Client)(nil)
+
+// DefaultMaxBytesBilled is the bytes billed by a query over which BigQuery
+// fails it, unless WithMaxBytesBilled is given.
+const DefaultMaxBytesBilled int64 = 1_000_000_000_000
+
+// ErrLegacySQLParameters is returned when query parameters are given to a
+// client for legacy SQL.
+var ErrLegacySQLParameters = errors.New("query parameters are not available in legacy SQL")
+
+type clientConfig struct {
+	endpoint       string
+	maxBytesBilled int64
+	session        bool
+	location       string
+	jobConfig      jobConfig
+}
+
+// jobConfig is applied to every query job.
+type jobConfig struct {
+	defaultProjectID string
+	defaultDatasetID string
+	labels           map[string]string
+	batchPriority    bool
+	legacySQL        bool
+}
+
+type ClientOption func(c *clientConfig)
+
+// WithEndpoint makes the client talk to the given endpoint, e.g. a local
+// BigQuery emulator, without credentials. host:port without a scheme is
+// treated as a plain HTTP endpoint.
+func WithEndpoint(endpoint string) ClientOption {
+	return func(c *clientConfig) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithMaxBytesBilled makes BigQuery fail queries which would bill more than the
+// given bytes instead of DefaultMaxBytesBilled. 0 means no limit.
+func WithMaxBytesBilled(bytes int64) ClientOption {
+	return func(c *clientConfig) {
+		c.maxBytesBilled = bytes
+	}
+}
+
+// WithLocation makes jobs run in the given location, e.g. "asia-northeast1".
+func WithLocation(location string) ClientOption {
+	return func(c *clientConfig) {
+		c.location = location
+	}
+}
+
+// WithDefaultDataset makes queries resolve table names without a dataset in
+// the given dataset. The project of the client is used if projectID is empty.
+func WithDefaultDataset(projectID, datasetID string) ClientOption {
+	return func(c *clientConfig) {
+		c.jobConfig.defaultProjectID = projectID
+		c.jobConfig.defaultDatasetID = datasetID
+	}
+}
+
+// WithLabels attaches the given labels to every job, e.g. for cost
+// attribution. Values should be made valid by LabelValue.
+func WithLabels(labels map[string]string) ClientOption {
+	return func(c *clientConfig) {
+		c.jobConfig.labels = labels
+	}
+}
+
+// WithBatchPriority makes queries run with batch priority, which are queued
+// until idle resources are available.
+func WithBatchPriority() ClientOption {
+	return func(c *clientConfig) {
+		c.jobConfig.batchPriority = true
+	}
+}
+
+// WithLegacySQL makes queries written in legacy SQL rather than GoogleSQL.
+// Query parameters and sessions are not available with it.
+func WithLegacySQL() ClientOption {
+	return func(c *clientConfig) {
+		c.jobConfig.legacySQL = true
+	}
 }
 
-func NewClient(ctx context.Context, projectID string) (*Client, error) {
-	api, err := bigquery.NewClient(ctx, projectID)
+func NewClient(ctx context.Context, projectID string, opts ...ClientOption) (*Client, error) {
+	cfg := clientConfig{
+		maxBytesBilled: DefaultMaxBytesBilled,
+	}
+
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	if cfg.session && cfg.jobConfig.legacySQL {
+		return nil, errors.New("sessions are not available in legacy SQL")
+	}
+
+	apiOpts := []option.ClientOption{}
+
+	if cfg.endpoint != "" {
+		apiOpts = append(apiOpts, option.WithEndpoint(endpointURL(cfg.endpoint)), option.WithoutAuthentication())
+	}
+
+	api, err := bigquery.NewClient(ctx, projectID, apiOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("create BigQuery client: %w", err)
 	}
 
+	// jobs created and looked up by the client are in this location
+	api.Location = cfg.location
+
+	if cfg.jobConfig.defaultDatasetID != "" && cfg.jobConfig.defaultProjectID == "" {
+		cfg.jobConfig.defaultProjectID = projectID
+	}
+
 	return &Client{
-		api: api,
+		api:            api,
+		maxBytesBilled: cfg.maxBytesBilled,
+		sessionEnabled: cfg.session,
+		jobConfig:      cfg.jobConfig,
 	}, nil
 }
 
+func endpointURL(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+
+	return "http://" + endpoint
+}
+
 func (c *Client) Close() error {
 	return c.api.Close()
 }
 
 type Result struct {
-	Query               string
-	Keys                []string
-	Rows                []map[string]bigquery.Value
+	Query string
+	// Parameters are the values given to the query parameters
+	Parameters []Parameter
+	Keys       []string
+	// Schema describes the columns in the same order as Keys. It is nil for
+	// results recorded before the schema was kept.
+	Schema []*Field
+	// Rows hold values in the same order as Keys, so that columns with the
+	// same name are kept. RECORD values are also positional, in the order of
+	// their nested fields.
+	Rows [][]bigquery.Value
+	// TotalRows is the number of rows in the whole result, which may be larger
+	// than len(Rows) if the rows are fetched page by page
+	TotalRows           int64
 	TotalBytesProcessed int64
-	DryRun              bool
-	EndTime             time.Time
+	// JobID, JobLocation, CacheHit, TotalBytesBilled, SlotMillis and Stages
+	// are taken from the statistics of the query job. They are empty for dry
+	// runs and table previews.
+	JobID            string
+	JobLocation      string
+	CacheHit         bool
+	TotalBytesBilled int64
+	SlotMillis       int64
+	Stages           []*Stage
+	// StatementType is the type of the query, e.g. "SELECT" or "SCRIPT"
+	StatementType string
+	// NumChildJobs is the number of statements run by a script
+	NumChildJobs int64
+	// Children are the results of statements run by a script, in the order
+	// they ran. Only the first page of rows is read for each of them. They are
+	// read by ScriptChildren on demand, so nil right after the script runs.
+	Children []*Result
+	// Destination is the fully-qualified name of the table the result is
+	// saved into. It is empty unless the query is run by SaveQuery.
+	Destination string
+	// RowsWritten is the number of rows written into Destination, counted with
+	// the table metadata before and after saving. It is 0 unless the query is
+	// run by SaveQuery.
+	RowsWritten int64
+	DryRun      bool
+	Cancelled   bool
+	EndTime     time.Time
+}
+
+// Job is a query job submitted to BigQuery.
+type Job struct {
+	query  string
+	params []Parameter
+	job    *bigquery.Job
+	// sessionGen is the session generation of the client when the job is
+	// configured
+	sessionGen uint64
 }
 
-func (c *Client) RunQuery(ctx context.Context, query string) (*Result, error) {
+// StartQuery submits the given query as a BigQuery job without waiting for its
+// completion.
+func (c *Client) StartQuery(ctx context.Context, query string, params ...Parameter) (*Job, error) {
+	if err := c.checkParameters(params); err != nil {
+		return nil, err
+	}
+
+	q, sessionGen := c.newQuery(query, params)
+
+	return c.runQuery(ctx, query, q, sessionGen, params)
+}
+
+// checkParameters rejects parameters before submitting a job if they are not
+// available.
+func (c *Client) checkParameters(params []Parameter) error {
+	if c.jobConfig.legacySQL && len(params) > 0 {
+		return ErrLegacySQLParameters
+	}
+
+	return nil
+}
+
+// newQuery configures a query job with the settings of the client. The
+// session generation at that time is returned as well.
+func (c *Client) newQuery(query string, params []Parameter) (*bigquery.Query, uint64) {
 	q := c.api.Query(query)
+	c.configureQuery(q)
+	q.MaxBytesBilled = c.maxBytesBilled
+	q.Parameters = queryParameters(params)
+	sessionGen := c.attachSession(q, true)
 
-	j, err := q.Run(ctx)
+	return q, sessionGen
+}
+
+// configureQuery applies the settings of the client to q. Every query job
+// created by the client must be configured by this.
+func (c *Client) configureQuery(q *bigquery.Query) {
+	q.DefaultProjectID = c.jobConfig.defaultProjectID
+	q.DefaultDatasetID = c.jobConfig.defaultDatasetID
+	q.Labels = c.jobConfig.labels
+	q.UseLegacySQL = c.jobConfig.legacySQL
+
+	if c.jobConfig.batchPriority {
+		q.Priority = bigquery.BatchPriority
+	}
+}
+
+// runQuery submits q, which is configured for query with params in the session
+// generation sessionGen.
+func (c *Client) runQuery(ctx context.Context, query string, q *bigquery.Query, sessionGen uint64, params []Parameter) (*Job, error) {
+	bj, err := q.Run(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("run BigQuery job: %w", err)
+		return nil, fmt.Errorf("run BigQuery job: %w", NewQueryError(err))
 	}
 
-	it, err := j.Read(ctx)
+	j := &Job{
+		query:      query,
+		params:     params,
+		job:        bj,
+		sessionGen: sessionGen,
+	}
+
+	c.keepSession(j)
+
+	return j, nil
+}
+
+// RunQuery runs the given query and waits for its result. If ctx is cancelled
+// while waiting, the BigQuery job is cancelled as well.
+func (c *Client) RunQuery(ctx context.Context, query string, params ...Parameter) (*Result, error) {
+	j, err := c.StartQuery(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := j.Read(ctx)
+	c.keepSession(j)
+
+	if err != nil {
+		return nil, j.cancelIfDone(ctx, err)
+	}
+
+	return r, nil
+}
+
+// StreamQuery runs the given query and waits for the first page of its result.
+// The rest of rows can be fetched through the returned RowStream while ctx is
+// alive. If ctx is cancelled while waiting, the BigQuery job is cancelled as
+// well.
+func (c *Client) StreamQuery(ctx context.Context, query string, pageSize int, params ...Parameter) (*Result, *RowStream, error) {
+	j, err := c.StartQuery(ctx, query, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, s, err := j.Stream(ctx, pageSize)
+	c.keepSession(j)
+
+	if err != nil {
+		return nil, nil, j.cancelIfDone(ctx, err)
+	}
+
+	return r, s, nil
+}
+
+// cancelIfDone cancels the job if err is caused by ctx being done.
+func (j *Job) cancelIfDone(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+
+	// ctx is already done, so use a fresh one to reach the API
+	if cerr := j.Cancel(context.Background()); cerr != nil {
+		return errors.Join(err, fmt.Errorf("cancel BigQuery job %s: %w", j.ID(), cerr))
+	}
+
+	return err
+}
+
+// ID returns the BigQuery job ID.
+func (j *Job) ID() string {
+	return j.job.ID()
+}
+
+// Cancel requests BigQuery to cancel the job. It returns without waiting for
+// the job to stop.
+func (j *Job) Cancel(ctx context.Context) error {
+	if err := j.job.Cancel(ctx); err != nil {
+		return fmt.Errorf("cancel BigQuery job: %w", err)
+	}
+
+	return nil
+}
+
+// Read waits for the job to finish and loads the whole result.
+func (j *Job) Read(ctx context.Context) (*Result, error) {
+	r, s, err := j.Stream(ctx, defaultPageSize)
 	if err != nil {
-		return nil, fmt.Errorf("read BigQuery job result: %w", err)
+		return nil, err
+	}
+
+	for !s.Done() {
+		rows, err := s.Next(defaultPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Rows = append(r.Rows, rows...)
 	}
 
+	return r, nil
+}
+
+// Stream waits for the job to finish and loads the first page of the result.
+// The returned RowStream fetches the rest with ctx.
+func (j *Job) Stream(ctx context.Context, pageSize int) (*Result, *RowStream, error) {
+	it, err := j.job.Read(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read BigQuery job result: %w", NewQueryError(err))
+	}
+
+	it.PageInfo().MaxSize = pageSize
+
 	keys := []string{}
 
 	for _, r := range it.Schema {
 		keys = append(keys, r.Name)
 	}
 
-	rows := []map[string]bigquery.Value{}
-
-	for {
-		var r map[string]bigquery.Value
+	rs := NewRowStream(func() ([]bigquery.Value, error) {
+		var r []bigquery.Value
 
 		if err := it.Next(&r); err != nil {
-			if err == iterator.Done {
-				break
-			}
-
-			return nil, fmt.Errorf("load result: %w", err)
+			return nil, err
 		}
 
-		rows = append(rows, r)
+		return r, nil
+	})
+
+	rows, err := rs.Next(pageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// waiting for the result does not refresh the status, which still has the
+	// statistics of the job when it is submitted
+	s, err := j.job.Status(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get the latest status: %w", NewQueryError(err))
 	}
 
-	s := j.LastStatus()
 	if err := s.Err(); err != nil {
-		return nil, fmt.Errorf("get the latest status: %w", err)
+		return nil, nil, fmt.Errorf("get the latest status: %w", NewQueryError(err))
 	}
 
-	return &Result{
-		Query:               query,
+	r := &Result{
+		Query:               j.query,
+		Parameters:          j.params,
 		Keys:                keys,
+		Schema:              NewSchema(it.Schema),
 		Rows:                rows,
+		TotalRows:           int64(it.TotalRows),
 		TotalBytesProcessed: s.Statistics.TotalBytesProcessed,
+		JobID:               j.ID(),
+		JobLocation:         j.job.Location(),
+		NumChildJobs:        s.Statistics.NumChildJobs,
 		EndTime:             s.Statistics.EndTime,
-	}, nil
+	}
+
+	qs, _ := s.Statistics.Details.(*bigquery.QueryStatistics)
+	setQueryStatistics(r, qs)
+
+	return r, rs, nil
 }
 
-func (c *Client) DryRunQuery(ctx context.Context, query string) (*Result, error) {
+// setQueryStatistics copies the statistics of a query job to r. qs may be nil.
+func setQueryStatistics(r *Result, qs *bigquery.QueryStatistics) {
+	if qs == nil {
+		return
+	}
+
+	r.StatementType = qs.StatementType
+	r.CacheHit = qs.CacheHit
+	r.TotalBytesBilled = qs.TotalBytesBilled
+	r.SlotMillis = qs.SlotMillis
+	r.Stages = NewStages(qs.QueryPlan)
+}
+
+func (c *Client) DryRunQuery(ctx context.Context, query string, params ...Parameter) (*Result, error) {
+	if err := c.checkParameters(params); err != nil {
+		return nil, err
+	}
+
 	q := c.api.Query(query)
+	c.configureQuery(q)
 	q.DryRun = true
+	q.Parameters = queryParameters(params)
+	c.attachSession(q, false)
 
 	j, err := q.Run(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("run BigQuery job: %w", err)
+		return nil, fmt.Errorf("run BigQuery job: %w", NewQueryError(err))
 	}
 
 	s := j.LastStatus()
 	if err := s.Err(); err != nil {
-		return nil, fmt.Errorf("get the latest status: %w", err)
+		return nil, fmt.Errorf("get the latest status: %w", NewQueryError(err))
+	}
+
+	var schema []*Field
+
+	// a dry run reports the schema of the result without running the query
+	if qs, ok := s.Statistics.Details.(*bigquery.QueryStatistics); ok {
+		schema = NewSchema(qs.Schema)
 	}
 
 	return &Result{
 		Query:               query,
+		Parameters:          params,
+		Schema:              schema,
 		TotalBytesProcessed: s.Statistics.TotalBytesProcessed,
 		EndTime:             s.Statistics.EndTime,
 		DryRun:              true,
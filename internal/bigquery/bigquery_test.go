@@ -0,0 +1,314 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	bqv2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestEndpointURL(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		endpoint string
+		want     string
+	}{
+		"host and port": {
+			endpoint: "localhost:9050",
+			want:     "http://localhost:9050",
+		},
+		"with scheme": {
+			endpoint: "https://bigquery.example.com/",
+			want:     "https://bigquery.example.com/",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := endpointURL(tc.endpoint); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClientNewQuery(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		opts []ClientOption
+		want bigquery.QueryConfig
+	}{
+		"defaults": {
+			want: bigquery.QueryConfig{
+				Q:              "select 1",
+				MaxBytesBilled: DefaultMaxBytesBilled,
+			},
+		},
+		"no limit": {
+			opts: []ClientOption{
+				WithMaxBytesBilled(0),
+			},
+			want: bigquery.QueryConfig{Q: "select 1"},
+		},
+		"settings": {
+			opts: []ClientOption{
+				WithMaxBytesBilled(1000),
+				WithDefaultDataset("", "analytics"),
+				WithLabels(map[string]string{"tool": "bqc"}),
+				WithBatchPriority(),
+				WithLegacySQL(),
+			},
+			want: bigquery.QueryConfig{
+				Q:                "select 1",
+				MaxBytesBilled:   1000,
+				DefaultProjectID: "test-project",
+				DefaultDatasetID: "analytics",
+				Labels:           map[string]string{"tool": "bqc"},
+				Priority:         bigquery.BatchPriority,
+				UseLegacySQL:     true,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]ClientOption{WithEndpoint("localhost:9050"), WithLocation("asia-northeast1")}, tc.opts...)
+
+			c, err := NewClient(context.Background(), "test-project", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.Close() })
+
+			if got := c.api.Location; got != "asia-northeast1" {
+				t.Errorf("want location: asia-northeast1, got: %q", got)
+			}
+
+			q, _ := c.newQuery("select 1", nil)
+
+			if diff := cmp.Diff(tc.want, q.QueryConfig, cmpopts.IgnoreUnexported(bigquery.QueryConfig{})); diff != "" {
+				t.Errorf("query config differs: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClient_legacySQLSession(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewClient(context.Background(), "test-project", WithEndpoint("localhost:9050"), WithLegacySQL(), WithSession())
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+func TestClientRunQuery_legacySQLParameters(t *testing.T) {
+	t.Parallel()
+
+	// no request is expected
+	api := newFakeAPI(t)
+
+	c := api.client(WithLegacySQL())
+	params := []Parameter{{Name: "n", Type: "INT64", Value: "1"}}
+
+	if _, err := c.RunQuery(context.Background(), "select @n", params...); !errors.Is(err, ErrLegacySQLParameters) {
+		t.Errorf("want ErrLegacySQLParameters from RunQuery, got: %v", err)
+	}
+
+	if _, err := c.DryRunQuery(context.Background(), "select @n", params...); !errors.Is(err, ErrLegacySQLParameters) {
+		t.Errorf("want ErrLegacySQLParameters from DryRunQuery, got: %v", err)
+	}
+
+	dst := &Destination{ProjectID: testProjectID, DatasetID: "d", TableID: "t"}
+
+	if _, err := c.SaveQuery(context.Background(), "select @n", dst, params...); !errors.Is(err, ErrLegacySQLParameters) {
+		t.Errorf("want ErrLegacySQLParameters from SaveQuery, got: %v", err)
+	}
+}
+
+func TestClientRunQuery(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	api.handleInsert()
+
+	api.handle("GET /projects/{p}/queries/{id}", func(r *http.Request) (int, any) {
+		return http.StatusOK, queryResults(
+			&bqv2.TableSchema{Fields: []*bqv2.TableFieldSchema{{Name: "n", Type: "INTEGER"}}},
+			row("1"),
+			row("2"),
+		)
+	})
+
+	// statistics are filled only after the job finishes
+	api.handle("GET /projects/{p}/jobs/{id}", func(r *http.Request) (int, any) {
+		return http.StatusOK, doneJob(r.PathValue("id"), &bqv2.JobStatistics{
+			CreationTime:        1700000000000,
+			EndTime:             1700000001000,
+			TotalBytesProcessed: 2000,
+			Query: &bqv2.JobStatistics2{
+				StatementType:    "SELECT",
+				TotalBytesBilled: 10485760,
+				TotalSlotMs:      1500,
+			},
+		})
+	})
+
+	c := api.client()
+
+	got, err := c.RunQuery(context.Background(), "select n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Result{
+		Query:  "select n",
+		Keys:   []string{"n"},
+		Schema: []*Field{{Name: "n", Type: "INTEGER", Mode: ModeNullable}},
+		Rows: [][]bigquery.Value{
+			{int64(1)},
+			{int64(2)},
+		},
+		TotalRows:           2,
+		TotalBytesProcessed: 2000,
+		TotalBytesBilled:    10485760,
+		SlotMillis:          1500,
+		StatementType:       "SELECT",
+		EndTime:             time.UnixMilli(1700000001000),
+	}
+
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Result{}, "JobID"), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("-want +got:\n%s", diff)
+	}
+
+	if got.JobID == "" {
+		t.Error("want the job ID recorded")
+	}
+}
+
+func TestClientRunQuery_cancel(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		cancelCode int
+		wantErr    string
+	}{
+		"cancelled": {
+			cancelCode: http.StatusOK,
+		},
+		"cancel fails": {
+			cancelCode: http.StatusBadRequest,
+			wantErr:    "cancel BigQuery job",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			api := newFakeAPI(t)
+			api.handleInsert()
+
+			polled := make(chan struct{})
+			var pollOnce sync.Once
+
+			// the job never completes
+			api.handle("GET /projects/{p}/queries/{id}", func(r *http.Request) (int, any) {
+				pollOnce.Do(func() { close(polled) })
+
+				return http.StatusOK, &bqv2.GetQueryResultsResponse{JobComplete: false}
+			})
+
+			cancelled := make(chan string, 1)
+
+			api.handle("POST /projects/{p}/jobs/{id}/cancel", func(r *http.Request) (int, any) {
+				cancelled <- r.PathValue("id")
+
+				if tc.cancelCode != http.StatusOK {
+					return tc.cancelCode, errorResponse(tc.cancelCode, "job is not cancellable")
+				}
+
+				return http.StatusOK, &bqv2.JobCancelResponse{}
+			})
+
+			c := api.client()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			errc := make(chan error, 1)
+
+			go func() {
+				_, err := c.RunQuery(ctx, "select 1")
+				errc <- err
+			}()
+
+			<-polled
+			cancel()
+
+			err := <-errc
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("want context.Canceled, got: %v", err)
+			}
+
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("want error containing %q, got: %v", tc.wantErr, err)
+			}
+
+			select {
+			case id := <-cancelled:
+				if id == "" {
+					t.Error("want the job ID in the cancel request")
+				}
+			default:
+				t.Error("want the job cancelled")
+			}
+		})
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	api.handleInsert()
+
+	var cancelledID string
+
+	api.handle("POST /projects/{p}/jobs/{id}/cancel", func(r *http.Request) (int, any) {
+		cancelledID = r.PathValue("id")
+		return http.StatusOK, &bqv2.JobCancelResponse{}
+	})
+
+	c := api.client()
+
+	j, err := c.StartQuery(context.Background(), "select 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.Cancel(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if cancelledID != j.ID() {
+		t.Errorf("want job %s cancelled, got: %q", j.ID(), cancelledID)
+	}
+}
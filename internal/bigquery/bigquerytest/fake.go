@@ -0,0 +1,507 @@
+// Package bigquerytest provides an in-memory implementation of
+// bigquery.Backend for tests.
+package bigquerytest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+// FakeProjectID is the project of FakeClient.
+const FakeProjectID = "fake-project"
+
+// FakeClient returns canned results and errors keyed by query text.
+type FakeClient struct {
+	mu      sync.Mutex
+	results map[string]*bigquery.Result
+	errors  map[string]error
+	// streamErrors are returned by streams once rows after the first page
+	// are read
+	streamErrors map[string]error
+	queries      []string
+	// params are parameters of queries, in the same order as queries
+	params [][]bigquery.Parameter
+	// sessions are IDs of the sessions queries ran in, in the same order as
+	// queries. "" if a query ran outside of a session.
+	sessions []string
+	tables   []*bigquery.Table
+	// destinations are where results are saved by SaveQuery, in order
+	destinations []bigquery.Destination
+	// previews are keyed by dataset.table
+	previews map[string]*bigquery.Result
+	// childReads is the number of calls of ScriptChildren
+	childReads int
+	// tableGets is the number of calls of GetTable
+	tableGets int
+
+	// hold blocks queries until it is closed or ctx is done
+	hold chan struct{}
+
+	// maxBytesBilled makes queries processing more bytes fail like BigQuery.
+	// 0 means no limit.
+	maxBytesBilled int64
+
+	sessionEnabled bool
+	session        *bigquery.Session
+	// sessionCount is the number of sessions created so far
+	sessionCount int
+}
+
+var _ bigquery.Backend = (*FakeClient)(nil)
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		results:      map[string]*bigquery.Result{},
+		errors:       map[string]error{},
+		streamErrors: map[string]error{},
+		queries:      []string{},
+		previews:     map[string]*bigquery.Result{},
+	}
+}
+
+// AddResult registers the result returned for the given query.
+func (c *FakeClient) AddResult(query string, r *bigquery.Result) *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[query] = r
+
+	return c
+}
+
+// AddError registers the error returned for the given query.
+func (c *FakeClient) AddError(query string, err error) *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errors[query] = err
+
+	return c
+}
+
+// AddStreamError registers the error returned by the stream of the given query
+// after the rows following the first page are read.
+func (c *FakeClient) AddStreamError(query string, err error) *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.streamErrors[query] = err
+
+	return c
+}
+
+// Hold makes subsequent queries and reads of streams block until Release is called or their
+// context is done.
+func (c *FakeClient) Hold() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hold = make(chan struct{})
+}
+
+// Release unblocks queries held by Hold.
+func (c *FakeClient) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hold != nil {
+		close(c.hold)
+		c.hold = nil
+	}
+}
+
+// SetMaxBytesBilled makes queries processing more than the given bytes fail
+// like Client with bigquery.WithMaxBytesBilled. Dry runs are not limited.
+func (c *FakeClient) SetMaxBytesBilled(bytes int64) *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytesBilled = bytes
+
+	return c
+}
+
+// EnableSession makes queries run in a session, which is created by the first
+// query that is not a dry run.
+func (c *FakeClient) EnableSession() *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sessionEnabled = true
+
+	return c
+}
+
+// Sessions returns IDs of the sessions queries received so far ran in, in
+// order.
+func (c *FakeClient) Sessions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.sessions...)
+}
+
+func (c *FakeClient) SessionEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.sessionEnabled
+}
+
+func (c *FakeClient) Session() *bigquery.Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.session
+}
+
+func (c *FakeClient) EndSession(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		return bigquery.ErrNoSession
+	}
+
+	c.session = nil
+
+	return nil
+}
+
+// Parameters returns parameters of queries received so far, in order.
+func (c *FakeClient) Parameters() [][]bigquery.Parameter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([][]bigquery.Parameter{}, c.params...)
+}
+
+// Queries returns queries received so far, in order.
+func (c *FakeClient) Queries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.queries...)
+}
+
+func (c *FakeClient) RunQuery(ctx context.Context, query string, params ...bigquery.Parameter) (*bigquery.Result, error) {
+	return c.run(ctx, query, params, false)
+}
+
+// StreamQuery returns the first pageSize rows of the canned result, and a
+// stream over the rest.
+func (c *FakeClient) StreamQuery(ctx context.Context, query string, pageSize int, params ...bigquery.Parameter) (*bigquery.Result, *bigquery.RowStream, error) {
+	r, err := c.run(ctx, query, params, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	streamErr, seok := c.streamErrors[query]
+	c.mu.Unlock()
+
+	rows := r.Rows
+	r.TotalRows = int64(len(rows))
+
+	if len(rows) > pageSize {
+		r.Rows = rows[:pageSize:pageSize]
+		rows = rows[pageSize:]
+	} else {
+		rows = nil
+	}
+
+	i := 0
+
+	s := bigquery.NewRowStream(func() ([]bigqueryapi.Value, error) {
+		c.mu.Lock()
+		hold := c.hold
+		c.mu.Unlock()
+
+		if hold != nil {
+			<-hold
+		}
+
+		if i >= len(rows) {
+			if seok {
+				return nil, streamErr
+			}
+
+			return nil, iterator.Done
+		}
+
+		i++
+
+		return rows[i-1], nil
+	})
+
+	return r, s, nil
+}
+
+func (c *FakeClient) DryRunQuery(ctx context.Context, query string, params ...bigquery.Parameter) (*bigquery.Result, error) {
+	return c.run(ctx, query, params, true)
+}
+
+func (c *FakeClient) run(ctx context.Context, query string, params []bigquery.Parameter, dryRun bool) (*bigquery.Result, error) {
+	c.mu.Lock()
+	c.queries = append(c.queries, query)
+	c.params = append(c.params, params)
+
+	if c.sessionEnabled && c.session == nil && !dryRun {
+		c.sessionCount++
+		c.session = &bigquery.Session{
+			ID:      fmt.Sprintf("fake-session-%d", c.sessionCount),
+			Created: time.Now(),
+		}
+	}
+
+	sessionID := ""
+	if c.session != nil {
+		sessionID = c.session.ID
+	}
+
+	c.sessions = append(c.sessions, sessionID)
+
+	hold := c.hold
+	r, rok := c.results[query]
+	err, eok := c.errors[query]
+	maxBytesBilled := c.maxBytesBilled
+	c.mu.Unlock()
+
+	if hold != nil {
+		select {
+		case <-hold:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("read BigQuery job result: %w", ctx.Err())
+		}
+	}
+
+	if eok {
+		return nil, err
+	}
+
+	if !rok {
+		return nil, fmt.Errorf("no result is registered for query: %q", query)
+	}
+
+	if !dryRun && maxBytesBilled > 0 && r.TotalBytesProcessed > maxBytesBilled {
+		return nil, &bigquery.QueryError{
+			Reason:  "bytesBilledLimitExceeded",
+			Message: fmt.Sprintf("Query exceeded limit for bytes billed: %d.", maxBytesBilled),
+		}
+	}
+
+	// copy to keep canned results untouched by callers
+	rr := *r
+	rr.Query = query
+	rr.Parameters = params
+
+	// like Client, statements of scripts are read by ScriptChildren
+	rr.NumChildJobs = int64(len(r.Children))
+	rr.Children = nil
+
+	if dryRun {
+		rr.Keys = nil
+		rr.Rows = nil
+		rr.NumChildJobs = 0
+		rr.DryRun = true
+	}
+
+	return &rr, nil
+}
+
+// ScriptChildren returns the children of the canned result of the script. Like
+// queries, it is blocked by Hold.
+func (c *FakeClient) ScriptChildren(ctx context.Context, script *bigquery.Result, pageSize int) ([]*bigquery.Result, error) {
+	c.mu.Lock()
+	c.childReads++
+
+	hold := c.hold
+	r, ok := c.results[script.Query]
+	c.mu.Unlock()
+
+	if hold != nil {
+		select {
+		case <-hold:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("list child jobs: %w", ctx.Err())
+		}
+	}
+
+	if !ok || len(r.Children) == 0 {
+		return nil, fmt.Errorf("no statement result is registered for script: %q", script.Query)
+	}
+
+	return append([]*bigquery.Result{}, r.Children...), nil
+}
+
+// ChildReads returns the number of calls of ScriptChildren.
+func (c *FakeClient) ChildReads() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.childReads
+}
+
+// SaveQuery returns the canned result of the query, and registers the
+// destination as a table holding its rows. Like Client, it fails with
+// bigquery.WriteEmpty if the table exists.
+func (c *FakeClient) SaveQuery(ctx context.Context, query string, dst *bigquery.Destination, params ...bigquery.Parameter) (*bigquery.Result, error) {
+	r, err := c.run(ctx, query, params, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := slices.IndexFunc(c.tables, func(t *bigquery.Table) bool {
+		return t.ProjectID == dst.ProjectID && t.DatasetID == dst.DatasetID && t.TableID == dst.TableID
+	})
+
+	if i >= 0 && dst.WriteDisposition == bigquery.WriteEmpty {
+		return nil, fmt.Errorf("table %s already exists", dst)
+	}
+
+	if i < 0 {
+		c.tables = append(c.tables, &bigquery.Table{
+			ProjectID: dst.ProjectID,
+			DatasetID: dst.DatasetID,
+			TableID:   dst.TableID,
+			Type:      "TABLE",
+		})
+		i = len(c.tables) - 1
+	}
+
+	t := c.tables[i]
+	written := uint64(len(r.Rows))
+
+	if dst.WriteDisposition == bigquery.WriteAppend {
+		t.NumRows += written
+	} else {
+		t.NumRows = written
+	}
+
+	c.destinations = append(c.destinations, *dst)
+
+	r.TotalRows = int64(t.NumRows)
+	r.RowsWritten = int64(written)
+	r.Rows = r.Rows[:min(len(r.Rows), 1)]
+	r.Destination = dst.String()
+
+	return r, nil
+}
+
+// Destinations returns where results are saved by SaveQuery so far, in order.
+func (c *FakeClient) Destinations() []bigquery.Destination {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]bigquery.Destination{}, c.destinations...)
+}
+
+// AddTable registers a table returned by catalog methods. Its dataset is
+// registered as well.
+func (c *FakeClient) AddTable(t *bigquery.Table) *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tables = append(c.tables, t)
+
+	return c
+}
+
+// ProjectID returns the project of the fake client.
+func (c *FakeClient) ProjectID() string {
+	return FakeProjectID
+}
+
+func (c *FakeClient) ListDatasets(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := []string{}
+
+	for _, t := range c.tables {
+		if !slices.Contains(ids, t.DatasetID) {
+			ids = append(ids, t.DatasetID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *FakeClient) ListTables(ctx context.Context, datasetID string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := []string{}
+
+	for _, t := range c.tables {
+		if t.DatasetID == datasetID {
+			ids = append(ids, t.TableID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *FakeClient) GetTable(ctx context.Context, datasetID, tableID string) (*bigquery.Table, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tableGets++
+
+	for _, t := range c.tables {
+		if t.DatasetID == datasetID && t.TableID == tableID {
+			tt := *t
+			if tt.ProjectID == "" {
+				tt.ProjectID = FakeProjectID
+			}
+
+			return &tt, nil
+		}
+	}
+
+	return nil, fmt.Errorf("table %s.%s is not found", datasetID, tableID)
+}
+
+// TableGets returns the number of calls of GetTable.
+func (c *FakeClient) TableGets() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tableGets
+}
+
+// AddPreview registers rows returned by PreviewTable for the table.
+func (c *FakeClient) AddPreview(datasetID, tableID string, r *bigquery.Result) *FakeClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.previews[datasetID+"."+tableID] = r
+
+	return c
+}
+
+func (c *FakeClient) PreviewTable(ctx context.Context, datasetID, tableID string, maxRows int) (*bigquery.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.previews[datasetID+"."+tableID]
+	if !ok {
+		return nil, fmt.Errorf("no preview is registered for table: %s.%s", datasetID, tableID)
+	}
+
+	rr := *r
+	rr.Rows = rr.Rows[:min(len(rr.Rows), maxRows)]
+
+	return &rr, nil
+}
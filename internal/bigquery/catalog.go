@@ -0,0 +1,194 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+)
+
+// CatalogBackend lists datasets and tables of the project. It is implemented by
+// Client, and by bigquerytest.FakeClient for tests.
+type CatalogBackend interface {
+	ProjectID() string
+	ListDatasets(ctx context.Context) ([]string, error)
+	ListTables(ctx context.Context, datasetID string) ([]string, error)
+	GetTable(ctx context.Context, datasetID, tableID string) (*Table, error)
+	PreviewTable(ctx context.Context, datasetID, tableID string, maxRows int) (*Result, error)
+}
+
+// Table describes a table or a view.
+type Table struct {
+	ProjectID string
+	DatasetID string
+	TableID   string
+	// Type is "TABLE", "VIEW", "MATERIALIZED_VIEW", "EXTERNAL" or "SNAPSHOT"
+	Type        string
+	Description string
+	Schema      []*Field
+	// Partitioning describes how the table is partitioned, e.g. "DAY by
+	// created_at". It is empty if the table is not partitioned.
+	Partitioning string
+	// Clustering are the columns the table is clustered by
+	Clustering []string
+	// NumRows and NumBytes are the size of the table, excluding the streaming
+	// buffer. They are 0 for views.
+	NumRows      uint64
+	NumBytes     int64
+	CreationTime time.Time
+	// LastModifiedTime is when the table or its data was modified last
+	LastModifiedTime time.Time
+}
+
+// FullName returns the fully qualified name of the table, which can be used in
+// queries with backticks.
+func (t *Table) FullName() string {
+	return fmt.Sprintf("%s.%s.%s", t.ProjectID, t.DatasetID, t.TableID)
+}
+
+// ProjectID returns the project where queries run.
+func (c *Client) ProjectID() string {
+	return c.api.Project()
+}
+
+// ListDatasets returns IDs of datasets in the project.
+func (c *Client) ListDatasets(ctx context.Context) ([]string, error) {
+	ids := []string{}
+
+	it := c.api.Datasets(ctx)
+
+	for {
+		ds, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("list datasets: %w", err)
+		}
+
+		ids = append(ids, ds.DatasetID)
+	}
+
+	return ids, nil
+}
+
+// ListTables returns IDs of tables and views in the dataset.
+func (c *Client) ListTables(ctx context.Context, datasetID string) ([]string, error) {
+	ids := []string{}
+
+	it := c.api.Dataset(datasetID).Tables(ctx)
+
+	for {
+		t, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("list tables in %s: %w", datasetID, err)
+		}
+
+		ids = append(ids, t.TableID)
+	}
+
+	return ids, nil
+}
+
+// GetTable returns the metadata of the table.
+func (c *Client) GetTable(ctx context.Context, datasetID, tableID string) (*Table, error) {
+	md, err := c.api.Dataset(datasetID).Table(tableID).Metadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get metadata of %s.%s: %w", datasetID, tableID, err)
+	}
+
+	t := &Table{
+		ProjectID:        c.api.Project(),
+		DatasetID:        datasetID,
+		TableID:          tableID,
+		Type:             string(md.Type),
+		Description:      md.Description,
+		Schema:           NewSchema(md.Schema),
+		Partitioning:     partitioning(md),
+		NumRows:          md.NumRows,
+		NumBytes:         md.NumBytes,
+		CreationTime:     md.CreationTime,
+		LastModifiedTime: md.LastModifiedTime,
+	}
+
+	if md.Clustering != nil {
+		t.Clustering = md.Clustering.Fields
+	}
+
+	return t, nil
+}
+
+// PreviewTable reads up to maxRows rows of the table with the tabledata.list
+// API, which is free unlike SELECT *. Views cannot be previewed.
+func (c *Client) PreviewTable(ctx context.Context, datasetID, tableID string, maxRows int) (*Result, error) {
+	it := c.api.Dataset(datasetID).Table(tableID).Read(ctx)
+	it.PageInfo().MaxSize = maxRows
+
+	rs := NewRowStream(func() ([]bigquery.Value, error) {
+		var r []bigquery.Value
+
+		if err := it.Next(&r); err != nil {
+			return nil, err
+		}
+
+		return r, nil
+	})
+
+	rows, err := rs.Next(maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("preview %s.%s: %w", datasetID, tableID, err)
+	}
+
+	// the schema is known after the first page is fetched
+	keys := []string{}
+
+	for _, f := range it.Schema {
+		keys = append(keys, f.Name)
+	}
+
+	return &Result{
+		Keys:      keys,
+		Schema:    NewSchema(it.Schema),
+		Rows:      rows,
+		TotalRows: int64(it.TotalRows),
+		EndTime:   time.Now(),
+	}, nil
+}
+
+// partitioning describes the partitioning of the table. It returns an empty
+// string if the table is not partitioned.
+func partitioning(md *bigquery.TableMetadata) string {
+	switch {
+	case md.TimePartitioning != nil:
+		typ := md.TimePartitioning.Type
+		if typ == "" {
+			typ = bigquery.DayPartitioningType
+		}
+
+		field := md.TimePartitioning.Field
+		if field == "" {
+			field = "_PARTITIONTIME"
+		}
+
+		return fmt.Sprintf("%s by %s", typ, field)
+
+	case md.RangePartitioning != nil:
+		r := md.RangePartitioning.Range
+		if r == nil {
+			return fmt.Sprintf("RANGE by %s", md.RangePartitioning.Field)
+		}
+
+		return fmt.Sprintf("RANGE by %s from %d to %d every %d", md.RangePartitioning.Field, r.Start, r.End, r.Interval)
+
+	default:
+		return ""
+	}
+}
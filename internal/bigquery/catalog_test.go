@@ -0,0 +1,142 @@
+package bigquery
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	bqv2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestPartitioning(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		md   *bigquery.TableMetadata
+		want string
+	}{
+		"not partitioned": {
+			md:   &bigquery.TableMetadata{},
+			want: "",
+		},
+		"time unit column": {
+			md: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.MonthPartitioningType, Field: "created_at"},
+			},
+			want: "MONTH by created_at",
+		},
+		"ingestion time": {
+			md: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{},
+			},
+			want: "DAY by _PARTITIONTIME",
+		},
+		"integer range": {
+			md: &bigquery.TableMetadata{
+				RangePartitioning: &bigquery.RangePartitioning{
+					Field: "customer_id",
+					Range: &bigquery.RangePartitioningRange{Start: 0, End: 100, Interval: 10},
+				},
+			},
+			want: "RANGE by customer_id from 0 to 100 every 10",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := partitioning(tc.md); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClientPreviewTable(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+
+	api.handle("GET /projects/{p}/datasets/shop/tables/orders", func(r *http.Request) (int, any) {
+		return http.StatusOK, &bqv2.Table{
+			Schema: &bqv2.TableSchema{
+				Fields: []*bqv2.TableFieldSchema{
+					{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+					{Name: "status", Type: "STRING", Mode: "NULLABLE"},
+				},
+			},
+		}
+	})
+
+	var maxResults []string
+
+	api.handle("GET /projects/{p}/datasets/shop/tables/orders/data", func(r *http.Request) (int, any) {
+		maxResults = append(maxResults, r.URL.Query().Get("maxResults"))
+
+		// more rows than requested are not returned, and the next page is
+		// never fetched
+		return http.StatusOK, &bqv2.TableDataList{
+			Rows: []*bqv2.TableRow{
+				{F: []*bqv2.TableCell{{V: "1"}, {V: "paid"}}},
+				{F: []*bqv2.TableCell{{V: "2"}, {V: nil}}},
+			},
+			TotalRows: 5,
+			PageToken: "next",
+		}
+	})
+
+	c := api.client()
+
+	got, err := c.PreviewTable(context.Background(), "shop", "orders", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Result{
+		Keys: []string{"id", "status"},
+		Schema: []*Field{
+			{Name: "id", Type: "INTEGER", Mode: ModeRequired},
+			{Name: "status", Type: "STRING", Mode: ModeNullable},
+		},
+		Rows: [][]bigquery.Value{
+			{int64(1), "paid"},
+			{int64(2), nil},
+		},
+		TotalRows: 5,
+	}
+
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Result{}, "EndTime")); diff != "" {
+		t.Errorf("-want +got:\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"2"}, maxResults); diff != "" {
+		t.Errorf("want one tabledata.list call with maxResults, -want +got:\n%s", diff)
+	}
+}
+
+func TestClientPreviewTable_error(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+
+	api.handle("GET /projects/{p}/datasets/shop/tables/{t}", func(r *http.Request) (int, any) {
+		return http.StatusOK, &bqv2.Table{Schema: &bqv2.TableSchema{}}
+	})
+
+	api.handle("GET /projects/{p}/datasets/shop/tables/{t}/data", func(r *http.Request) (int, any) {
+		return http.StatusBadRequest, errorResponse(http.StatusBadRequest, "Cannot list a table of type VIEW.")
+	})
+
+	c := api.client()
+
+	_, err := c.PreviewTable(context.Background(), "shop", "orders_view", 10)
+	if err == nil || !strings.Contains(err.Error(), "preview shop.orders_view: ") || !strings.Contains(err.Error(), "Cannot list a table of type VIEW.") {
+		t.Errorf("want preview error, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// WriteEmpty makes saving fail if the destination table exists. BigQuery
+	// itself accepts an existing empty table, so SaveQuery checks the existence
+	// beforehand.
+	WriteEmpty = string(bigquery.WriteEmpty)
+	// WriteTruncate replaces the data of the destination table
+	WriteTruncate = string(bigquery.WriteTruncate)
+	// WriteAppend appends the result to the destination table
+	WriteAppend = string(bigquery.WriteAppend)
+)
+
+// Destination is a table query results are saved into. The table is created
+// if it does not exist.
+type Destination struct {
+	ProjectID string
+	DatasetID string
+	TableID   string
+	// WriteDisposition is WriteEmpty, WriteTruncate or WriteAppend
+	WriteDisposition string
+}
+
+// String returns the fully-qualified name of the table.
+func (d *Destination) String() string {
+	return d.ProjectID + "." + d.DatasetID + "." + d.TableID
+}
+
+// ParseDestination parses a table name, "dataset.table" or
+// "project.dataset.table" optionally quoted with backticks. projectID is used
+// if the name has no project.
+func ParseDestination(name, projectID, writeDisposition string) (*Destination, error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(name), "`"), ".")
+
+	if len(parts) == 2 {
+		parts = append([]string{projectID}, parts...)
+	}
+
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid table name %q, want dataset.table or project.dataset.table", name)
+	}
+
+	return &Destination{
+		ProjectID:        parts[0],
+		DatasetID:        parts[1],
+		TableID:          parts[2],
+		WriteDisposition: writeDisposition,
+	}, nil
+}
+
+// SaveQuery runs the given query with dst as its destination table, and waits
+// for the job to finish. Only the first row of the result is read, and
+// RowsWritten of the returned result is the number of rows written into the
+// table. If ctx is cancelled while waiting, the BigQuery job is cancelled as
+// well.
+func (c *Client) SaveQuery(ctx context.Context, query string, dst *Destination, params ...Parameter) (*Result, error) {
+	if err := c.checkParameters(params); err != nil {
+		return nil, err
+	}
+
+	t := c.api.DatasetInProject(dst.ProjectID, dst.DatasetID).Table(dst.TableID)
+
+	before, exists, err := tableRows(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("get metadata of %s: %w", dst, err)
+	}
+
+	if exists && dst.WriteDisposition == WriteEmpty {
+		return nil, fmt.Errorf("table %s already exists", dst)
+	}
+
+	q, sessionGen := c.newQuery(query, params)
+	q.Dst = t
+	q.WriteDisposition = bigquery.TableWriteDisposition(dst.WriteDisposition)
+	q.CreateDisposition = bigquery.CreateIfNeeded
+
+	j, err := c.runQuery(ctx, query, q, sessionGen, params)
+	if err != nil {
+		return nil, err
+	}
+
+	r, _, err := j.Stream(ctx, 1)
+	c.keepSession(j)
+
+	if err != nil {
+		return nil, j.cancelIfDone(ctx, err)
+	}
+
+	after, _, err := tableRows(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("get metadata of %s: %w", dst, err)
+	}
+
+	r.Destination = dst.String()
+	r.RowsWritten = int64(after)
+
+	if dst.WriteDisposition == WriteAppend {
+		r.RowsWritten -= int64(before)
+	}
+
+	return r, nil
+}
+
+// tableRows returns the number of rows in the table, and whether it exists.
+func tableRows(ctx context.Context, t *bigquery.Table) (uint64, bool, error) {
+	md, err := t.Metadata(ctx)
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return md.NumRows, true, nil
+}
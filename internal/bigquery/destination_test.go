@@ -0,0 +1,198 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	bqv2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestParseDestination(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		name    string
+		want    *Destination
+		wantErr bool
+	}{
+		"dataset and table": {
+			name: "sales.daily",
+			want: &Destination{ProjectID: "my-project", DatasetID: "sales", TableID: "daily", WriteDisposition: WriteAppend},
+		},
+		"fully-qualified": {
+			name: "other-project.sales.daily",
+			want: &Destination{ProjectID: "other-project", DatasetID: "sales", TableID: "daily", WriteDisposition: WriteAppend},
+		},
+		"quoted": {
+			name: " `other-project.sales.daily` ",
+			want: &Destination{ProjectID: "other-project", DatasetID: "sales", TableID: "daily", WriteDisposition: WriteAppend},
+		},
+		"table only": {
+			name:    "daily",
+			wantErr: true,
+		},
+		"empty dataset": {
+			name:    ".daily",
+			wantErr: true,
+		},
+		"too many parts": {
+			name:    "a.b.c.d",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseDestination(tc.name, "my-project", WriteAppend)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error, got: %#v", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClientSaveQuery(t *testing.T) {
+	t.Parallel()
+
+	// the query writes 3 rows
+	testcases := map[string]struct {
+		disposition string
+		// rowsBefore is the number of rows in the destination before saving.
+		// nil if the table does not exist.
+		rowsBefore      *uint64
+		rowsAfter       uint64
+		wantRowsWritten int64
+		wantErr         string
+	}{
+		"truncate": {
+			disposition:     WriteTruncate,
+			rowsBefore:      ptr(uint64(2)),
+			rowsAfter:       3,
+			wantRowsWritten: 3,
+		},
+		"append": {
+			disposition:     WriteAppend,
+			rowsBefore:      ptr(uint64(2)),
+			rowsAfter:       5,
+			wantRowsWritten: 3,
+		},
+		"append to new table": {
+			disposition:     WriteAppend,
+			rowsAfter:       3,
+			wantRowsWritten: 3,
+		},
+		"empty": {
+			disposition:     WriteEmpty,
+			rowsAfter:       3,
+			wantRowsWritten: 3,
+		},
+		"empty but exists": {
+			disposition: WriteEmpty,
+			rowsBefore:  ptr(uint64(0)),
+			wantErr:     "table test-project.sales.daily already exists",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			api := newFakeAPI(t)
+
+			inserted := false
+
+			api.handle("GET /projects/{p}/datasets/sales/tables/daily", func(r *http.Request) (int, any) {
+				if inserted {
+					return http.StatusOK, &bqv2.Table{NumRows: tc.rowsAfter}
+				}
+
+				if tc.rowsBefore == nil {
+					return http.StatusNotFound, errorResponse(http.StatusNotFound, "Not found: Table test-project:sales.daily")
+				}
+
+				return http.StatusOK, &bqv2.Table{NumRows: *tc.rowsBefore}
+			})
+
+			api.handle("POST /projects/{p}/jobs", func(r *http.Request) (int, any) {
+				inserted = true
+
+				var j bqv2.Job
+				if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+					t.Errorf("decode job: %s", err)
+				}
+
+				qc := j.Configuration.Query
+
+				want := &bqv2.TableReference{ProjectId: testProjectID, DatasetId: "sales", TableId: "daily"}
+				if diff := cmp.Diff(want, qc.DestinationTable); diff != "" {
+					t.Errorf("destination: (-want +got)\n%s", diff)
+				}
+
+				if qc.WriteDisposition != tc.disposition || qc.CreateDisposition != "CREATE_IF_NEEDED" {
+					t.Errorf("want dispositions %s and CREATE_IF_NEEDED, got: %s and %s", tc.disposition, qc.WriteDisposition, qc.CreateDisposition)
+				}
+
+				j.Status = &bqv2.JobStatus{State: "RUNNING"}
+
+				return http.StatusOK, &j
+			})
+
+			api.handle("GET /projects/{p}/queries/{id}", func(r *http.Request) (int, any) {
+				return http.StatusOK, queryResults(&bqv2.TableSchema{Fields: []*bqv2.TableFieldSchema{{Name: "n", Type: "INTEGER"}}}, row("1"))
+			})
+
+			api.handle("GET /projects/{p}/jobs/{id}", func(r *http.Request) (int, any) {
+				return http.StatusOK, doneJob(r.PathValue("id"), &bqv2.JobStatistics{Query: &bqv2.JobStatistics2{StatementType: "SELECT"}})
+			})
+
+			c := api.client()
+
+			dst := &Destination{ProjectID: testProjectID, DatasetID: "sales", TableID: "daily", WriteDisposition: tc.disposition}
+
+			got, err := c.SaveQuery(context.Background(), "select n from t", dst)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("want error containing %q, got: %v", tc.wantErr, err)
+				}
+
+				if inserted {
+					t.Error("want no job submitted")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.RowsWritten != tc.wantRowsWritten || got.Destination != "test-project.sales.daily" {
+				t.Errorf("want %d row(s) written into test-project.sales.daily, got: %d row(s) written into %s", tc.wantRowsWritten, got.RowsWritten, got.Destination)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
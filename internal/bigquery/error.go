@@ -0,0 +1,83 @@
+package bigquery
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+// errorPositionRegexp matches the position of an error in the query, e.g. "at [12:5]".
+var errorPositionRegexp = regexp.MustCompile(`\[(\d+):(\d+)\]`)
+
+// QueryError is an error reported by BigQuery for a query.
+type QueryError struct {
+	// Reason is a short error code, e.g. "invalidQuery"
+	Reason  string
+	Message string
+	// Line and Column are 1-based position of the error in the query. They
+	// are 0 if BigQuery does not report the position.
+	Line   int
+	Column int
+
+	err error
+}
+
+// NewQueryError extracts the details of err returned by the BigQuery API. err
+// is returned as is if it does not come from BigQuery.
+func NewQueryError(err error) error {
+	var (
+		bqErr  *bigquery.Error
+		apiErr *googleapi.Error
+		qe     *QueryError
+	)
+
+	e := &QueryError{err: err}
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &qe):
+		return err
+	case errors.As(err, &bqErr):
+		e.Reason = bqErr.Reason
+		e.Message = bqErr.Message
+	case errors.As(err, &apiErr):
+		e.Message = apiErr.Message
+
+		if len(apiErr.Errors) > 0 {
+			e.Reason = apiErr.Errors[0].Reason
+			e.Message = apiErr.Errors[0].Message
+		}
+	default:
+		return err
+	}
+
+	if m := errorPositionRegexp.FindStringSubmatch(e.Message); m != nil {
+		// the pattern guarantees digits, so Atoi fails only on overflow
+		line, lerr := strconv.Atoi(m[1])
+		column, cerr := strconv.Atoi(m[2])
+
+		if lerr == nil && cerr == nil {
+			e.Line = line
+			e.Column = column
+		}
+	}
+
+	return e
+}
+
+func (e *QueryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.err
+}
+
+// HasPosition reports whether BigQuery reported where the error is.
+func (e *QueryError) HasPosition() bool {
+	return e.Line > 0 && e.Column > 0
+}
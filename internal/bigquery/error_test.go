@@ -0,0 +1,92 @@
+package bigquery
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/api/googleapi"
+)
+
+func TestNewQueryError(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		err  error
+		want *QueryError
+	}{
+		"job error with position": {
+			err: &bigquery.Error{
+				Reason:  "invalidQuery",
+				Message: "Syntax error: Unexpected identifier \"foo\" at [12:5]",
+			},
+			want: &QueryError{
+				Reason:  "invalidQuery",
+				Message: "Syntax error: Unexpected identifier \"foo\" at [12:5]",
+				Line:    12,
+				Column:  5,
+			},
+		},
+		"wrapped API error": {
+			err: fmt.Errorf("run: %w", &googleapi.Error{
+				Code:    400,
+				Message: "Unrecognized name: bar at [1:8]",
+				Errors: []googleapi.ErrorItem{
+					{
+						Reason:  "invalidQuery",
+						Message: "Unrecognized name: bar at [1:8]",
+					},
+				},
+			}),
+			want: &QueryError{
+				Reason:  "invalidQuery",
+				Message: "Unrecognized name: bar at [1:8]",
+				Line:    1,
+				Column:  8,
+			},
+		},
+		"without position": {
+			err: &bigquery.Error{
+				Reason:  "accessDenied",
+				Message: "Access Denied: Project foo",
+			},
+			want: &QueryError{
+				Reason:  "accessDenied",
+				Message: "Access Denied: Project foo",
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got *QueryError
+			if !errors.As(NewQueryError(tc.err), &got) {
+				t.Fatalf("want QueryError, got: %#v", NewQueryError(tc.err))
+			}
+
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(QueryError{})); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+
+			if !errors.Is(got, tc.err) {
+				t.Errorf("want the original error wrapped")
+			}
+		})
+	}
+}
+
+func TestNewQueryError_other(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("connection refused")
+
+	if got := NewQueryError(err); got != err {
+		t.Errorf("want the original error, got: %#v", got)
+	}
+}
@@ -0,0 +1,46 @@
+package bigquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLabelLength is the maximum length of label keys and values
+const maxLabelLength = 63
+
+// LabelValue converts s into a valid label value, which consists of lowercase
+// letters, digits, "_" and "-". Other characters are replaced with "_", e.g.
+// "DOMAIN\John.Doe" becomes "domain_john_doe".
+func LabelValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, s)
+
+	if len(s) > maxLabelLength {
+		s = s[:maxLabelLength]
+	}
+
+	return s
+}
+
+// ValidateLabel checks whether the label can be attached to jobs. Keys and
+// values must be valid as converted by LabelValue, and keys must start with a
+// lowercase letter.
+func ValidateLabel(key, value string) error {
+	if key == "" || key[0] < 'a' || key[0] > 'z' || LabelValue(key) != key {
+		return fmt.Errorf("invalid label key %q, use up to %d lowercase letters, digits, '_' and '-' starting with a letter", key, maxLabelLength)
+	}
+
+	if LabelValue(value) != value {
+		return fmt.Errorf("invalid value %q of label %s, use up to %d lowercase letters, digits, '_' and '-'", value, key, maxLabelLength)
+	}
+
+	return nil
+}
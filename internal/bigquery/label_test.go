@@ -0,0 +1,103 @@
+package bigquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelValue(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		s    string
+		want string
+	}{
+		"valid": {
+			s:    "john_doe-2",
+			want: "john_doe-2",
+		},
+		"uppercase and symbols": {
+			s:    `DOMAIN\John.Doe`,
+			want: "domain_john_doe",
+		},
+		"non-ASCII": {
+			s:    "ユーザー",
+			want: "____",
+		},
+		"too long": {
+			s:    strings.Repeat("a", 70),
+			want: strings.Repeat("a", 63),
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := LabelValue(tc.s); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateLabel(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		"valid": {
+			key:   "team",
+			value: "data-platform_2",
+		},
+		"empty value": {
+			key:   "team",
+			value: "",
+		},
+		"empty key": {
+			key:     "",
+			value:   "data",
+			wantErr: true,
+		},
+		"key starting with a digit": {
+			key:     "1team",
+			value:   "data",
+			wantErr: true,
+		},
+		"uppercase key": {
+			key:     "Team",
+			value:   "data",
+			wantErr: true,
+		},
+		"value with a space": {
+			key:     "team",
+			value:   "data platform",
+			wantErr: true,
+		},
+		"too long value": {
+			key:     "team",
+			value:   strings.Repeat("a", 64),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateLabel(tc.key, tc.value)
+			if tc.wantErr && err == nil {
+				t.Error("want error, got nil")
+			}
+
+			if !tc.wantErr && err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+		})
+	}
+}
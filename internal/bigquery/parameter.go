@@ -0,0 +1,55 @@
+package bigquery
+
+import (
+	"cloud.google.com/go/bigquery"
+)
+
+// ParameterTypes are the types a query parameter can be given.
+var ParameterTypes = []string{
+	"STRING",
+	"INT64",
+	"FLOAT64",
+	"NUMERIC",
+	"BIGNUMERIC",
+	"BOOL",
+	"DATE",
+	"DATETIME",
+	"TIME",
+	"TIMESTAMP",
+	"BYTES",
+	"JSON",
+}
+
+// Parameter is a named query parameter, e.g. @start_date in a query.
+type Parameter struct {
+	// Name is the parameter name without "@"
+	Name string
+	// Type is one of ParameterTypes
+	Type string
+	// Value is the value in the literal format of the type, e.g. "2024-01-02"
+	// for DATE. It is validated by BigQuery.
+	Value string
+}
+
+// queryParameters converts params into typed parameters of the BigQuery API.
+func queryParameters(params []Parameter) []bigquery.QueryParameter {
+	if len(params) == 0 {
+		return nil
+	}
+
+	qps := make([]bigquery.QueryParameter, 0, len(params))
+
+	for _, p := range params {
+		qps = append(qps, bigquery.QueryParameter{
+			Name: p.Name,
+			Value: &bigquery.QueryParameterValue{
+				Type: bigquery.StandardSQLDataType{
+					TypeKind: p.Type,
+				},
+				Value: p.Value,
+			},
+		})
+	}
+
+	return qps
+}
@@ -0,0 +1,42 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQueryParameters(t *testing.T) {
+	t.Parallel()
+
+	params := []Parameter{
+		{Name: "start_date", Type: "DATE", Value: "2024-01-02"},
+		{Name: "user_id", Type: "INT64", Value: "42"},
+	}
+
+	want := []bigquery.QueryParameter{
+		{
+			Name: "start_date",
+			Value: &bigquery.QueryParameterValue{
+				Type:  bigquery.StandardSQLDataType{TypeKind: "DATE"},
+				Value: "2024-01-02",
+			},
+		},
+		{
+			Name: "user_id",
+			Value: &bigquery.QueryParameterValue{
+				Type:  bigquery.StandardSQLDataType{TypeKind: "INT64"},
+				Value: "42",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, queryParameters(params)); diff != "" {
+		t.Errorf("-want +got:\n%s", diff)
+	}
+
+	if got := queryParameters(nil); got != nil {
+		t.Errorf("want nil for no parameters, got: %#v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package bigquery
+
+import (
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Stage is a stage of the query plan of a job.
+type Stage struct {
+	ID     int64
+	Name   string
+	Status string
+	// InputStages are IDs of the stages whose output this stage reads
+	InputStages []int64
+	StartTime   time.Time
+	EndTime     time.Time
+	// RecordsRead and RecordsWritten are the input and output rows
+	RecordsRead        int64
+	RecordsWritten     int64
+	ShuffleOutputBytes int64
+	// Ratios are the time the average shard spent waiting for slots, reading
+	// input, computing and writing output, relative to the longest of them
+	// among all stages
+	WaitRatioAvg    float64
+	ReadRatioAvg    float64
+	ComputeRatioAvg float64
+	WriteRatioAvg   float64
+	// Steps are the operations in the stage, e.g. "READ" or "AGGREGATE"
+	Steps []string
+}
+
+// NewStages converts the query plan returned by the BigQuery API.
+func NewStages(plan []*bigquery.ExplainQueryStage) []*Stage {
+	if plan == nil {
+		return nil
+	}
+
+	stages := make([]*Stage, 0, len(plan))
+
+	for _, s := range plan {
+		steps := make([]string, 0, len(s.Steps))
+		for _, st := range s.Steps {
+			steps = append(steps, st.Kind)
+		}
+
+		stages = append(stages, &Stage{
+			ID:                 s.ID,
+			Name:               s.Name,
+			Status:             s.Status,
+			InputStages:        s.InputStages,
+			StartTime:          s.StartTime,
+			EndTime:            s.EndTime,
+			RecordsRead:        s.RecordsRead,
+			RecordsWritten:     s.RecordsWritten,
+			ShuffleOutputBytes: s.ShuffleOutputBytes,
+			WaitRatioAvg:       s.WaitRatioAvg,
+			ReadRatioAvg:       s.ReadRatioAvg,
+			ComputeRatioAvg:    s.ComputeRatioAvg,
+			WriteRatioAvg:      s.WriteRatioAvg,
+			Steps:              steps,
+		})
+	}
+
+	return stages
+}
+
+// Duration returns how long the stage ran. It is 0 if the stage has not
+// finished.
+func (s *Stage) Duration() time.Duration {
+	if s.StartTime.IsZero() || s.EndTime.IsZero() {
+		return 0
+	}
+
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// DominantStage returns the index of the stage which ran the longest, or -1 if
+// no stage has finished.
+func DominantStage(stages []*Stage) int {
+	dominant := -1
+
+	var longest time.Duration
+
+	for i, s := range stages {
+		if d := s.Duration(); d > longest {
+			dominant = i
+			longest = d
+		}
+	}
+
+	return dominant
+}
@@ -0,0 +1,96 @@
+package bigquery
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewStages(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	plan := []*bigquery.ExplainQueryStage{
+		{
+			ID:              1,
+			Name:            "S00: Input",
+			Status:          "COMPLETE",
+			StartTime:       start,
+			EndTime:         start.Add(2 * time.Second),
+			RecordsRead:     1000,
+			RecordsWritten:  10,
+			ComputeRatioAvg: 0.5,
+			Steps: []*bigquery.ExplainQueryStep{
+				{Kind: "READ", Substeps: []string{"$1:id"}},
+				{Kind: "WRITE", Substeps: []string{"$1"}},
+			},
+		},
+	}
+
+	want := []*Stage{
+		{
+			ID:              1,
+			Name:            "S00: Input",
+			Status:          "COMPLETE",
+			StartTime:       start,
+			EndTime:         start.Add(2 * time.Second),
+			RecordsRead:     1000,
+			RecordsWritten:  10,
+			ComputeRatioAvg: 0.5,
+			Steps:           []string{"READ", "WRITE"},
+		},
+	}
+
+	got := NewStages(plan)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("-want +got:\n%s", diff)
+	}
+
+	if d := got[0].Duration(); d != 2*time.Second {
+		t.Errorf("want duration 2s, got: %s", d)
+	}
+}
+
+func TestDominantStage(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testcases := map[string]struct {
+		stages []*Stage
+		want   int
+	}{
+		"longest stage": {
+			stages: []*Stage{
+				{StartTime: start, EndTime: start.Add(time.Second)},
+				{StartTime: start, EndTime: start.Add(3 * time.Second)},
+				{StartTime: start, EndTime: start.Add(2 * time.Second)},
+			},
+			want: 1,
+		},
+		"no finished stage": {
+			stages: []*Stage{
+				{StartTime: start},
+			},
+			want: -1,
+		},
+		"no stage": {
+			stages: nil,
+			want:   -1,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := DominantStage(tc.stages); got != tc.want {
+				t.Errorf("want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package bigquery
+
+import (
+	"cloud.google.com/go/bigquery"
+)
+
+const (
+	ModeNullable = "NULLABLE"
+	ModeRequired = "REQUIRED"
+	ModeRepeated = "REPEATED"
+)
+
+// Field describes a column of a query result.
+type Field struct {
+	Name string
+	// Type is a BigQuery data type, e.g. "STRING", "INTEGER" or "RECORD"
+	Type string
+	// Mode is one of ModeNullable, ModeRequired and ModeRepeated
+	Mode        string
+	Description string
+	// Fields are nested fields of a RECORD
+	Fields []*Field
+}
+
+// NewSchema converts the schema returned by the BigQuery API.
+func NewSchema(s bigquery.Schema) []*Field {
+	if s == nil {
+		return nil
+	}
+
+	fs := make([]*Field, 0, len(s))
+
+	for _, f := range s {
+		mode := ModeNullable
+
+		switch {
+		case f.Repeated:
+			mode = ModeRepeated
+		case f.Required:
+			mode = ModeRequired
+		}
+
+		fs = append(fs, &Field{
+			Name:        f.Name,
+			Type:        string(f.Type),
+			Mode:        mode,
+			Description: f.Description,
+			Fields:      NewSchema(f.Schema),
+		})
+	}
+
+	return fs
+}
+
+// IsRepeated reports whether the field is an array.
+func (f *Field) IsRepeated() bool {
+	return f.Mode == ModeRepeated
+}
+
+// IsNumeric reports whether values of the field are numbers.
+func (f *Field) IsNumeric() bool {
+	switch bigquery.FieldType(f.Type) {
+	case bigquery.IntegerFieldType, bigquery.FloatFieldType, bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		return !f.IsRepeated()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewSchema(t *testing.T) {
+	t.Parallel()
+
+	s := bigquery.Schema{
+		{
+			Name:     "id",
+			Type:     bigquery.IntegerFieldType,
+			Required: true,
+		},
+		{
+			Name:        "tags",
+			Type:        bigquery.RecordFieldType,
+			Repeated:    true,
+			Description: "tags of the item",
+			Schema: bigquery.Schema{
+				{
+					Name: "name",
+					Type: bigquery.StringFieldType,
+				},
+			},
+		},
+	}
+
+	want := []*Field{
+		{
+			Name: "id",
+			Type: "INTEGER",
+			Mode: ModeRequired,
+		},
+		{
+			Name:        "tags",
+			Type:        "RECORD",
+			Mode:        ModeRepeated,
+			Description: "tags of the item",
+			Fields: []*Field{
+				{
+					Name: "name",
+					Type: "STRING",
+					Mode: ModeNullable,
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, NewSchema(s)); diff != "" {
+		t.Errorf("-want +got:\n%s", diff)
+	}
+}
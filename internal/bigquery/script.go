@@ -0,0 +1,106 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// StatementTypeSelect is the statement type of SELECT queries
+	StatementTypeSelect = "SELECT"
+	// StatementTypeScript is the statement type of multi-statement queries
+	StatementTypeScript = "SCRIPT"
+)
+
+// ScriptChildren reads results of the statements run by the script, in the
+// order they ran. Up to pageSize rows are read for each SELECT statement.
+func (c *Client) ScriptChildren(ctx context.Context, script *Result, pageSize int) ([]*Result, error) {
+	j, err := c.api.JobFromIDLocation(ctx, script.JobID, script.JobLocation)
+	if err != nil {
+		return nil, fmt.Errorf("get script job %s: %w", script.JobID, NewQueryError(err))
+	}
+
+	return (&Job{query: script.Query, job: j}).children(ctx, pageSize)
+}
+
+// children reads results of the statements run by the script job, in the order
+// they ran. Up to pageSize rows are read for each SELECT statement.
+func (j *Job) children(ctx context.Context, pageSize int) ([]*Result, error) {
+	jobs := []*bigquery.Job{}
+
+	it := j.job.Children(ctx)
+
+	for {
+		cj, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("list child jobs of %s: %w", j.ID(), err)
+		}
+
+		jobs = append(jobs, cj)
+	}
+
+	// jobs are listed from the newest. They are not sorted by creation time,
+	// which can be the same for statements run in the same millisecond.
+	slices.Reverse(jobs)
+
+	results := make([]*Result, 0, len(jobs))
+
+	for _, cj := range jobs {
+		r, err := readChild(ctx, cj, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+// readChild reads the result of a statement in a script. Rows are read only for
+// SELECT statements, since other statements have no result set.
+func readChild(ctx context.Context, cj *bigquery.Job, pageSize int) (*Result, error) {
+	query := ""
+
+	if cfg, err := cj.Config(); err == nil {
+		if qc, ok := cfg.(*bigquery.QueryConfig); ok {
+			query = qc.Q
+		}
+	}
+
+	s := cj.LastStatus()
+	if s == nil || s.Statistics == nil {
+		return &Result{Query: query, JobID: cj.ID()}, nil
+	}
+
+	qs, _ := s.Statistics.Details.(*bigquery.QueryStatistics)
+
+	if qs != nil && qs.StatementType == StatementTypeSelect {
+		r, _, err := (&Job{query: query, job: cj}).Stream(ctx, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("read result of child job %s: %w", cj.ID(), err)
+		}
+
+		return r, nil
+	}
+
+	r := &Result{
+		Query:               query,
+		TotalBytesProcessed: s.Statistics.TotalBytesProcessed,
+		JobID:               cj.ID(),
+		EndTime:             s.Statistics.EndTime,
+	}
+
+	setQueryStatistics(r, qs)
+
+	return r, nil
+}
@@ -0,0 +1,236 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	bqv2 "google.golang.org/api/bigquery/v2"
+)
+
+// fakeScript serves a script job whose child jobs are given in the order they
+// are listed by BigQuery, i.e. from the newest.
+type fakeScript struct {
+	children []*bqv2.JobListJobs
+	// results are the responses of getQueryResults of child jobs by job ID
+	results map[string]func() (int, any)
+}
+
+func (s *fakeScript) serve(api *fakeAPI) {
+	var parentID string
+
+	api.handle("POST /projects/{p}/jobs", func(r *http.Request) (int, any) {
+		var j bqv2.Job
+		if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+			api.t.Errorf("decode job: %s", err)
+		}
+
+		parentID = j.JobReference.JobId
+
+		j.Status = &bqv2.JobStatus{State: "RUNNING"}
+
+		return http.StatusOK, &j
+	})
+
+	api.handle("GET /projects/{p}/jobs", func(r *http.Request) (int, any) {
+		if got := r.URL.Query().Get("parentJobId"); got != parentID {
+			api.t.Errorf("want child jobs of %s listed, got: %q", parentID, got)
+		}
+
+		return http.StatusOK, &bqv2.JobList{Jobs: s.children}
+	})
+
+	api.handle("GET /projects/{p}/jobs/{id}", func(r *http.Request) (int, any) {
+		id := r.PathValue("id")
+
+		for _, c := range s.children {
+			if c.JobReference.JobId == id {
+				return http.StatusOK, &bqv2.Job{JobReference: c.JobReference, Status: c.Status, Statistics: c.Statistics}
+			}
+		}
+
+		return http.StatusOK, doneJob(id, &bqv2.JobStatistics{
+			NumChildJobs: int64(len(s.children)),
+			Query:        &bqv2.JobStatistics2{StatementType: StatementTypeScript},
+		})
+	})
+
+	api.handle("GET /projects/{p}/queries/{id}", func(r *http.Request) (int, any) {
+		if id := r.PathValue("id"); id != parentID {
+			return s.results[id]()
+		}
+
+		// the result of the last statement
+		return http.StatusOK, queryResults(&bqv2.TableSchema{Fields: []*bqv2.TableFieldSchema{{Name: "n", Type: "INTEGER"}}}, row("2"))
+	})
+}
+
+// childJob returns a child job listed by BigQuery.
+func childJob(id, query, statementType string, created int64, errorResult *bqv2.ErrorProto) *bqv2.JobListJobs {
+	return &bqv2.JobListJobs{
+		JobReference:  &bqv2.JobReference{ProjectId: testProjectID, JobId: id},
+		Configuration: &bqv2.JobConfiguration{Query: &bqv2.JobConfigurationQuery{Query: query}},
+		Status:        &bqv2.JobStatus{State: "DONE", ErrorResult: errorResult},
+		Statistics: &bqv2.JobStatistics{
+			CreationTime:        created,
+			EndTime:             created + 1000,
+			TotalBytesProcessed: 100,
+			Query:               &bqv2.JobStatistics2{StatementType: statementType},
+		},
+	}
+}
+
+func TestClientScriptChildren(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+
+	script := &fakeScript{
+		children: []*bqv2.JobListJobs{
+			childJob("child_2", "select n from t", "SELECT", 1700000002000, nil),
+			childJob("child_1", "insert into t values (1)", "INSERT", 1700000001000, nil),
+		},
+		results: map[string]func() (int, any){
+			"child_2": func() (int, any) {
+				return http.StatusOK, queryResults(&bqv2.TableSchema{Fields: []*bqv2.TableFieldSchema{{Name: "n", Type: "INTEGER"}}}, row("1"))
+			},
+		},
+	}
+	script.serve(api)
+
+	c := api.client()
+
+	got, err := c.RunQuery(context.Background(), "insert into t values (1); select n from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.StatementType != StatementTypeScript {
+		t.Errorf("want statement type %s, got: %q", StatementTypeScript, got.StatementType)
+	}
+
+	if got.NumChildJobs != 2 {
+		t.Errorf("want 2 child jobs, got: %d", got.NumChildJobs)
+	}
+
+	// statements are not read with the result of the script
+	if got.Children != nil {
+		t.Errorf("want no children read, got: %v", got.Children)
+	}
+
+	if slices.Contains(api.received(), "GET /projects/"+testProjectID+"/jobs") {
+		t.Error("want child jobs not listed by RunQuery")
+	}
+
+	children, err := c.ScriptChildren(context.Background(), got, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// children are in the order they ran, and rows are read only for SELECT
+	want := []*Result{
+		{
+			Query:               "insert into t values (1)",
+			TotalBytesProcessed: 100,
+			JobID:               "child_1",
+			StatementType:       "INSERT",
+			EndTime:             time.UnixMilli(1700000002000),
+		},
+		{
+			Query:               "select n from t",
+			Keys:                []string{"n"},
+			Schema:              []*Field{{Name: "n", Type: "INTEGER", Mode: ModeNullable}},
+			Rows:                [][]bigquery.Value{{int64(1)}},
+			TotalRows:           1,
+			TotalBytesProcessed: 100,
+			JobID:               "child_2",
+			StatementType:       "SELECT",
+			EndTime:             time.UnixMilli(1700000003000),
+		},
+	}
+
+	if diff := cmp.Diff(want, children, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("children: -want +got:\n%s", diff)
+	}
+}
+
+func TestClientScriptChildren_sameCreationTime(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+
+	script := &fakeScript{
+		children: []*bqv2.JobListJobs{
+			childJob("child_3", "set n = 3", "ASSIGNMENT", 1700000001000, nil),
+			childJob("child_2", "set n = 2", "ASSIGNMENT", 1700000001000, nil),
+			childJob("child_1", "declare n int64", "DECLARE", 1700000001000, nil),
+		},
+	}
+	script.serve(api)
+
+	c := api.client()
+
+	r, err := c.RunQuery(context.Background(), "declare n int64; set n = 2; set n = 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	children, err := c.ScriptChildren(context.Background(), r, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, c := range children {
+		got = append(got, c.JobID)
+	}
+
+	if diff := cmp.Diff([]string{"child_1", "child_2", "child_3"}, got); diff != "" {
+		t.Errorf("order of children: -want +got:\n%s", diff)
+	}
+}
+
+func TestClientScriptChildren_failingChild(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+
+	// the script goes on after the error is handled by an EXCEPTION block
+	script := &fakeScript{
+		children: []*bqv2.JobListJobs{
+			childJob("child_2", "select 1 / 0", "SELECT", 1700000002000, &bqv2.ErrorProto{Reason: "invalidQuery", Message: "division by zero: 1 / 0"}),
+			childJob("child_1", "insert into t values (1)", "INSERT", 1700000001000, nil),
+		},
+		results: map[string]func() (int, any){
+			"child_2": func() (int, any) {
+				return http.StatusBadRequest, errorResponse(http.StatusBadRequest, "division by zero: 1 / 0")
+			},
+		},
+	}
+	script.serve(api)
+
+	c := api.client()
+
+	r, err := c.RunQuery(context.Background(), "begin insert into t values (1); select 1 / 0; exception when error then select 2 as n; end")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ScriptChildren(context.Background(), r, 10)
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	for _, s := range []string{"read result of child job child_2", "division by zero: 1 / 0"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("want error containing %q, got: %s", s, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// sessionIDProperty is the connection property which runs a job in a session
+const sessionIDProperty = "session_id"
+
+// ErrNoSession is returned when the session is ended before any query creates
+// it.
+var ErrNoSession = errors.New("no session is created yet")
+
+// SessionBackend runs queries in a BigQuery session, so that TEMP tables and
+// variables are kept across queries. It is implemented by Client, and by
+// bigquerytest.FakeClient for tests.
+type SessionBackend interface {
+	// SessionEnabled reports whether queries run in a session
+	SessionEnabled() bool
+	// Session returns the current session, or nil if no query has created it
+	Session() *Session
+	// EndSession terminates the current session. The next query creates a new
+	// one.
+	EndSession(ctx context.Context) error
+}
+
+// Session is a BigQuery session created by the first query run in session
+// mode.
+type Session struct {
+	ID      string
+	Created time.Time
+}
+
+// Age returns how long the session has lived at now.
+func (s *Session) Age(now time.Time) time.Duration {
+	return now.Sub(s.Created)
+}
+
+// WithSession makes the client run queries in a BigQuery session, which is
+// created by the first query.
+func WithSession() ClientOption {
+	return func(c *clientConfig) {
+		c.session = true
+	}
+}
+
+func (c *Client) SessionEnabled() bool {
+	return c.sessionEnabled
+}
+
+func (c *Client) Session() *Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.session
+}
+
+// EndSession aborts the current session with BQ.ABORT_SESSION. The session is
+// forgotten even if aborting fails, e.g. because it has already expired.
+func (c *Client) EndSession(ctx context.Context) error {
+	c.mu.Lock()
+	s := c.session
+	c.session = nil
+	// jobs still running in s must not keep it
+	c.sessionGen++
+	c.mu.Unlock()
+
+	if s == nil {
+		return ErrNoSession
+	}
+
+	q := c.api.Query("CALL BQ.ABORT_SESSION()")
+	c.configureQuery(q)
+	// the procedure is not available in legacy SQL
+	q.UseLegacySQL = false
+	q.ConnectionProperties = sessionProperties(s.ID)
+
+	j, err := q.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("end session %s: %w", s.ID, NewQueryError(err))
+	}
+
+	st, err := j.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("end session %s: %w", s.ID, NewQueryError(err))
+	}
+
+	if err := st.Err(); err != nil {
+		return fmt.Errorf("end session %s: %w", s.ID, NewQueryError(err))
+	}
+
+	return nil
+}
+
+// attachSession makes q run in the current session. If there is none yet, q
+// creates it if create is true. Dry runs cannot create a session, so they run
+// outside of it until a query creates one. The current session generation is
+// returned.
+func (c *Client) attachSession(q *bigquery.Query, create bool) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.sessionEnabled {
+		return c.sessionGen
+	}
+
+	if c.session != nil {
+		q.ConnectionProperties = sessionProperties(c.session.ID)
+		return c.sessionGen
+	}
+
+	q.CreateSession = create
+
+	return c.sessionGen
+}
+
+// keepSession remembers the session created by the job. Jobs configured before
+// the session is ended are ignored, since their session is no longer alive.
+func (c *Client) keepSession(j *Job) {
+	if !c.sessionEnabled {
+		return
+	}
+
+	s := j.job.LastStatus()
+	if s == nil || s.Statistics == nil || s.Statistics.SessionInfo == nil || s.Statistics.SessionInfo.SessionID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil || j.sessionGen != c.sessionGen {
+		return
+	}
+
+	created := s.Statistics.CreationTime
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	c.session = &Session{
+		ID:      s.Statistics.SessionInfo.SessionID,
+		Created: created,
+	}
+}
+
+func sessionProperties(id string) []*bigquery.ConnectionProperty {
+	return []*bigquery.ConnectionProperty{
+		{
+			Key:   sessionIDProperty,
+			Value: id,
+		},
+	}
+}
@@ -0,0 +1,154 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+	bqv2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClientAttachSession(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		opts       []ClientOption
+		session    *Session
+		create     bool
+		wantCreate bool
+		wantProps  []*bigquery.ConnectionProperty
+	}{
+		"disabled": {
+			create: true,
+		},
+		"no session yet": {
+			opts:       []ClientOption{WithSession()},
+			create:     true,
+			wantCreate: true,
+		},
+		"no session yet for a dry run": {
+			opts:   []ClientOption{WithSession()},
+			create: false,
+		},
+		"session": {
+			opts:    []ClientOption{WithSession()},
+			session: &Session{ID: "abc"},
+			create:  true,
+			wantProps: []*bigquery.ConnectionProperty{
+				{Key: "session_id", Value: "abc"},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]ClientOption{WithEndpoint("localhost:9050")}, tc.opts...)
+
+			c, err := NewClient(context.Background(), "test-project", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.Close() })
+
+			c.session = tc.session
+
+			q := c.api.Query("select 1")
+			c.attachSession(q, tc.create)
+
+			if q.CreateSession != tc.wantCreate {
+				t.Errorf("want CreateSession: %t, got: %t", tc.wantCreate, q.CreateSession)
+			}
+
+			if diff := cmp.Diff(tc.wantProps, q.ConnectionProperties); diff != "" {
+				t.Errorf("connection properties differ: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClientEndSession_running(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+
+	var (
+		mu      sync.Mutex
+		queryID string
+	)
+
+	inserted := make(chan struct{})
+	ended := make(chan struct{})
+
+	api.handle("POST /projects/{p}/jobs", func(r *http.Request) (int, any) {
+		var j bqv2.Job
+		if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+			t.Errorf("decode job: %s", err)
+		}
+
+		if strings.Contains(j.Configuration.Query.Query, "ABORT_SESSION") {
+			return http.StatusOK, doneJob(j.JobReference.JobId, &bqv2.JobStatistics{})
+		}
+
+		mu.Lock()
+		queryID = j.JobReference.JobId
+		mu.Unlock()
+		close(inserted)
+
+		j.Status = &bqv2.JobStatus{State: "RUNNING"}
+
+		return http.StatusOK, &j
+	})
+
+	// the query finishes after the session is ended
+	api.handle("GET /projects/{p}/queries/{id}", func(r *http.Request) (int, any) {
+		mu.Lock()
+		running := r.PathValue("id") == queryID
+		mu.Unlock()
+
+		if running {
+			<-ended
+		}
+
+		return http.StatusOK, queryResults(&bqv2.TableSchema{})
+	})
+
+	api.handle("GET /projects/{p}/jobs/{id}", func(r *http.Request) (int, any) {
+		return http.StatusOK, doneJob(r.PathValue("id"), &bqv2.JobStatistics{
+			SessionInfo: &bqv2.SessionInfo{SessionId: "abc"},
+		})
+	})
+
+	c := api.client(WithSession())
+	c.session = &Session{ID: "abc"}
+
+	errc := make(chan error, 1)
+
+	go func() {
+		_, err := c.RunQuery(context.Background(), "select 1")
+		errc <- err
+	}()
+
+	<-inserted
+
+	if err := c.EndSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	close(ended)
+
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	if s := c.Session(); s != nil {
+		t.Errorf("want the ended session forgotten, got: %#v", s)
+	}
+}
@@ -0,0 +1,53 @@
+package bigquery
+
+import (
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	defaultPageSize = 10000
+)
+
+// RowStream fetches rows of a query result on demand.
+type RowStream struct {
+	next func() ([]bigquery.Value, error)
+	done bool
+}
+
+// NewRowStream creates RowStream. next returns iterator.Done after the last row.
+func NewRowStream(next func() ([]bigquery.Value, error)) *RowStream {
+	return &RowStream{
+		next: next,
+	}
+}
+
+// Next fetches up to n rows. Fewer rows are returned once the stream reaches the
+// end of the result.
+func (s *RowStream) Next(n int) ([][]bigquery.Value, error) {
+	rows := [][]bigquery.Value{}
+
+	for len(rows) < n && !s.done {
+		r, err := s.next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				s.done = true
+				break
+			}
+
+			return rows, fmt.Errorf("load result: %w", err)
+		}
+
+		rows = append(rows, r)
+	}
+
+	return rows, nil
+}
+
+// Done reports whether all rows have been fetched.
+func (s *RowStream) Done() bool {
+	return s.done
+}
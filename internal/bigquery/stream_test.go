@@ -0,0 +1,74 @@
+package bigquery
+
+import (
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/iterator"
+)
+
+func TestRowStreamNext(t *testing.T) {
+	t.Parallel()
+
+	all := [][]bigquery.Value{
+		{int64(1)},
+		{int64(2)},
+		{int64(3)},
+	}
+
+	i := 0
+
+	s := NewRowStream(func() ([]bigquery.Value, error) {
+		if i >= len(all) {
+			return nil, iterator.Done
+		}
+
+		i++
+
+		return all[i-1], nil
+	})
+
+	got, err := s.Next(2)
+	if err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(all[:2], got); diff != "" {
+		t.Errorf("first page mismatch (-want +got):\n%s", diff)
+	}
+
+	if s.Done() {
+		t.Error("want stream not done after the first page")
+	}
+
+	got, err = s.Next(2)
+	if err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(all[2:], got); diff != "" {
+		t.Errorf("second page mismatch (-want +got):\n%s", diff)
+	}
+
+	if !s.Done() {
+		t.Error("want stream done after the last page")
+	}
+}
+
+func TestRowStreamNext_error(t *testing.T) {
+	t.Parallel()
+
+	s := NewRowStream(func() ([]bigquery.Value, error) {
+		return nil, errors.New("connection reset")
+	})
+
+	if _, err := s.Next(1); err == nil {
+		t.Error("want error, got nil")
+	}
+
+	if s.Done() {
+		t.Error("want stream not done on error")
+	}
+}
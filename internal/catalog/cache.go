@@ -0,0 +1,293 @@
+// Package catalog caches names of datasets, tables and columns of a project, so
+// that they can be completed without calling the BigQuery API on every key
+// stroke.
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	// defaultTTL is how long cached names are used without fetching them again
+	defaultTTL = 24 * time.Hour
+	// failureTTL is how long a failed fetch is returned again without calling
+	// the API, e.g. for a table which is not created yet
+	failureTTL = time.Minute
+)
+
+// Cache keeps the catalog of a project in a local database, one bucket per
+// project. Names are fetched from the backend when they are not cached or
+// expired.
+type Cache struct {
+	backend bigquery.CatalogBackend
+	db      *bolt.DB
+	bucket  []byte
+	ttl     time.Duration
+	tsFunc  func() time.Time
+
+	// fetching holds keys being fetched, to avoid fetching the same names twice.
+	// The channel is closed when the fetch is done.
+	fetching map[string]chan struct{}
+	// failures holds keys whose latest fetch failed. They are kept only in
+	// memory.
+	failures map[string]failure
+	mu       sync.Mutex
+}
+
+// failure is a failed fetch.
+type failure struct {
+	at  time.Time
+	err error
+}
+
+// entry is a cached list of names or columns.
+type entry struct {
+	FetchedAt time.Time
+	Names     []string
+	Fields    []*bigquery.Field
+}
+
+func NewCache(filename string, backend bigquery.CatalogBackend) (*Cache, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	bucket := backend.ProjectID()
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+
+		return err
+	}); err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("create bucket %s: %w", bucket, err)
+	}
+
+	return &Cache{
+		backend:  backend,
+		db:       db,
+		bucket:   []byte(bucket),
+		ttl:      defaultTTL,
+		tsFunc:   time.Now,
+		fetching: map[string]chan struct{}{},
+		failures: map[string]failure{},
+	}, nil
+}
+
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+// ProjectID returns the project whose catalog is cached.
+func (c *Cache) ProjectID() string {
+	return string(c.bucket)
+}
+
+// CachedDatasets returns cached dataset IDs, and whether they are fresh. It
+// never calls the API, so it can be called in the UI loop.
+func (c *Cache) CachedDatasets() ([]string, bool) {
+	e, fresh := c.get(datasetsKey())
+	if e == nil {
+		return nil, false
+	}
+
+	return e.Names, fresh
+}
+
+// CachedTables returns cached table IDs in the dataset, and whether they are
+// fresh.
+func (c *Cache) CachedTables(datasetID string) ([]string, bool) {
+	e, fresh := c.get(tablesKey(datasetID))
+	if e == nil {
+		return nil, false
+	}
+
+	return e.Names, fresh
+}
+
+// CachedColumns returns cached columns of the table, and whether they are
+// fresh.
+func (c *Cache) CachedColumns(datasetID, tableID string) ([]*bigquery.Field, bool) {
+	e, fresh := c.get(columnsKey(datasetID, tableID))
+	if e == nil {
+		return nil, false
+	}
+
+	return e.Fields, fresh
+}
+
+// FetchDatasets fetches dataset IDs and caches them.
+func (c *Cache) FetchDatasets(ctx context.Context) ([]string, error) {
+	e, err := c.fetch(datasetsKey(), func() (*entry, error) {
+		ids, err := c.backend.ListDatasets(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &entry{Names: ids}, nil
+	})
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	return e.Names, nil
+}
+
+// FetchTables fetches table IDs in the dataset and caches them.
+func (c *Cache) FetchTables(ctx context.Context, datasetID string) ([]string, error) {
+	e, err := c.fetch(tablesKey(datasetID), func() (*entry, error) {
+		ids, err := c.backend.ListTables(ctx, datasetID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &entry{Names: ids}, nil
+	})
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	return e.Names, nil
+}
+
+// FetchColumns fetches columns of the table and caches them.
+func (c *Cache) FetchColumns(ctx context.Context, datasetID, tableID string) ([]*bigquery.Field, error) {
+	e, err := c.fetch(columnsKey(datasetID, tableID), func() (*entry, error) {
+		t, err := c.backend.GetTable(ctx, datasetID, tableID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &entry{Fields: t.Schema}, nil
+	})
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	return e.Fields, nil
+}
+
+// fetch calls f and stores its result. If the key is being fetched by another
+// caller, it waits for that instead of calling f. If the key failed within
+// failureTTL, the same error is returned without calling f.
+func (c *Cache) fetch(key string, f func() (*entry, error)) (*entry, error) {
+	c.mu.Lock()
+	if fl, ok := c.failures[key]; ok && c.tsFunc().Sub(fl.at) < failureTTL {
+		c.mu.Unlock()
+
+		return nil, fl.err
+	}
+
+	if done, ok := c.fetching[key]; ok {
+		c.mu.Unlock()
+		<-done
+
+		// nil if the other fetch failed
+		e, _ := c.get(key)
+
+		return e, nil
+	}
+
+	done := make(chan struct{})
+	c.fetching[key] = done
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.fetching, key)
+		c.mu.Unlock()
+
+		close(done)
+	}()
+
+	e, err := f()
+
+	c.mu.Lock()
+	if err != nil {
+		c.failures[key] = failure{at: c.tsFunc(), err: err}
+	} else {
+		delete(c.failures, key)
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	e.FetchedAt = c.tsFunc()
+
+	if err := c.put(key, e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// get returns the cached entry, and whether it is fresh. It returns nil if the
+// entry is not cached or cannot be decoded.
+func (c *Cache) get(key string) (*entry, bool) {
+	var e *entry
+
+	// errors are treated as cache misses
+	_ = c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(c.bucket).Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+
+		var ee entry
+
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&ee); err != nil {
+			return err
+		}
+
+		e = &ee
+
+		return nil
+	})
+
+	if e == nil {
+		return nil, false
+	}
+
+	return e, c.tsFunc().Sub(e.FetchedAt) < c.ttl
+}
+
+func (c *Cache) put(key string, e *entry) error {
+	var v bytes.Buffer
+
+	if err := gob.NewEncoder(&v).Encode(e); err != nil {
+		return fmt.Errorf("encode catalog to gob: %w", err)
+	}
+
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(c.bucket).Put([]byte(key), v.Bytes())
+	}); err != nil {
+		return fmt.Errorf("save catalog: %w", err)
+	}
+
+	return nil
+}
+
+func datasetsKey() string {
+	return "datasets"
+}
+
+func tablesKey(datasetID string) string {
+	return "tables/" + datasetID
+}
+
+func columnsKey(datasetID, tableID string) string {
+	return "columns/" + datasetID + "/" + tableID
+}
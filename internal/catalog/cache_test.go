@@ -0,0 +1,167 @@
+package catalog
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/bigquery/bigquerytest"
+)
+
+func newFakeClient() *bigquerytest.FakeClient {
+	return bigquerytest.NewFakeClient().
+		AddTable(&bigquery.Table{
+			DatasetID: "shop",
+			TableID:   "orders",
+			Schema: []*bigquery.Field{
+				{Name: "id", Type: "INTEGER", Mode: bigquery.ModeRequired},
+			},
+		}).
+		AddTable(&bigquery.Table{DatasetID: "shop", TableID: "users"}).
+		AddTable(&bigquery.Table{DatasetID: "logs", TableID: "access"})
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "catalog.db")
+
+	c, err := NewCache(filename, newFakeClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, fresh := c.CachedDatasets(); fresh {
+		t.Error("want no datasets cached before fetching")
+	}
+
+	if _, err := c.FetchDatasets(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.FetchTables(ctx, "shop"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.FetchColumns(ctx, "shop", "orders"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// names are kept in the file
+	c, err = NewCache(filename, newFakeClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	datasets, fresh := c.CachedDatasets()
+	if !fresh {
+		t.Error("want datasets fresh")
+	}
+
+	if diff := cmp.Diff([]string{"shop", "logs"}, datasets); diff != "" {
+		t.Errorf("datasets: -want +got:\n%s", diff)
+	}
+
+	tables, _ := c.CachedTables("shop")
+	if diff := cmp.Diff([]string{"orders", "users"}, tables); diff != "" {
+		t.Errorf("tables: -want +got:\n%s", diff)
+	}
+
+	columns, _ := c.CachedColumns("shop", "orders")
+	if diff := cmp.Diff([]*bigquery.Field{{Name: "id", Type: "INTEGER", Mode: bigquery.ModeRequired}}, columns); diff != "" {
+		t.Errorf("columns: -want +got:\n%s", diff)
+	}
+
+	if tables, _ := c.CachedTables("logs"); tables != nil {
+		t.Errorf("want tables in logs not cached, got: %q", tables)
+	}
+
+	c.tsFunc = func() time.Time {
+		return time.Now().Add(defaultTTL)
+	}
+
+	if datasets, fresh := c.CachedDatasets(); fresh || len(datasets) != 2 {
+		t.Errorf("want expired datasets still returned, got: %q (fresh: %t)", datasets, fresh)
+	}
+}
+
+func TestCache_perProject(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "catalog.db")
+
+	c, err := NewCache(filename, newFakeClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.FetchDatasets(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+
+	c, err = NewCache(filename, otherProjectClient{newFakeClient()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if datasets, _ := c.CachedDatasets(); datasets != nil {
+		t.Errorf("want datasets of another project not returned, got: %q", datasets)
+	}
+}
+
+type otherProjectClient struct {
+	*bigquerytest.FakeClient
+}
+
+func (otherProjectClient) ProjectID() string {
+	return "other-project"
+}
+
+func TestCache_failure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fake := newFakeClient()
+
+	c, err := NewCache(filepath.Join(t.TempDir(), "catalog.db"), fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.FetchColumns(ctx, "shop", "missing"); err == nil {
+			t.Fatal("want error for a missing table, got nil")
+		}
+	}
+
+	if n := fake.TableGets(); n != 1 {
+		t.Errorf("want the failure cached, got %d calls", n)
+	}
+
+	c.tsFunc = func() time.Time {
+		return time.Now().Add(failureTTL)
+	}
+
+	if _, err := c.FetchColumns(ctx, "shop", "missing"); err == nil {
+		t.Fatal("want error for a missing table, got nil")
+	}
+
+	if n := fake.TableGets(); n != 2 {
+		t.Errorf("want the table fetched again after failureTTL, got %d calls", n)
+	}
+}
@@ -34,6 +34,19 @@ func (s *Checkpoint) Save(q string, ts time.Time) error {
 		return nil
 	}
 
+	return s.write(q, ts)
+}
+
+// Flush writes q regardless of when the checkpoint was saved last, e.g. when
+// the buffer is left or the application exits.
+func (s *Checkpoint) Flush(q string, ts time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(q, ts)
+}
+
+func (s *Checkpoint) write(q string, ts time.Time) error {
 	d := filepath.Dir(s.filename)
 
 	if _, err := os.Stat(d); os.IsNotExist(err) {
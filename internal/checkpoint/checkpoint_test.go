@@ -91,3 +91,34 @@ func TestSave_savedAtTooClose(t *testing.T) {
 		t.Errorf("lastSavedAt mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestFlush_savedAtTooClose(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "checkpoint.ckpt")
+	ts := time.Date(2023, 5, 21, 19, 0, 0, 0, time.UTC)
+
+	ckpt := &Checkpoint{
+		filename:    filename,
+		lastSavedAt: ts.Add(-1 * time.Second),
+	}
+
+	q := "q1"
+
+	if err := ckpt.Flush(q, ts); err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("file does not exist: %s", err)
+	}
+
+	if diff := cmp.Diff(q, string(got)); diff != "" {
+		t.Errorf("checkpoint body mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(ts, ckpt.lastSavedAt); diff != "" {
+		t.Errorf("lastSavedAt mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,177 @@
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// orderFilename keeps the order of buffers. It is never a valid buffer
+	// name, so it is not listed as a buffer.
+	orderFilename = "#order"
+)
+
+var (
+	nameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// Dir keeps checkpoints of multiple named buffers in a directory, one file per
+// buffer.
+type Dir struct {
+	dirname string
+
+	mu sync.Mutex
+}
+
+func NewDir(dirname string) *Dir {
+	return &Dir{
+		dirname: dirname,
+	}
+}
+
+// ValidateName checks whether name can be used as a buffer name. Names are
+// used as filenames as they are.
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid buffer name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+
+	return nil
+}
+
+// Names returns names of saved buffers in the order saved by SaveOrder.
+// Buffers missing from the order follow in lexical order.
+func (d *Dir) Names() ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	es, err := os.ReadDir(d.dirname)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+
+		return []string{}, fmt.Errorf("read directory: %w", err)
+	}
+
+	saved := map[string]bool{}
+
+	for _, e := range es {
+		if e.Type().IsRegular() && ValidateName(e.Name()) == nil {
+			saved[e.Name()] = true
+		}
+	}
+
+	order, err := os.ReadFile(filepath.Join(d.dirname, orderFilename))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return []string{}, fmt.Errorf("read order: %w", err)
+	}
+
+	names := []string{}
+
+	for _, name := range strings.Split(string(order), "\n") {
+		if saved[name] {
+			names = append(names, name)
+			delete(saved, name)
+		}
+	}
+
+	rest := []string{}
+
+	for name := range saved {
+		rest = append(rest, name)
+	}
+
+	sort.Strings(rest)
+
+	return append(names, rest...), nil
+}
+
+// SaveOrder records the order of buffers, which is returned by Names.
+func (d *Dir) SaveOrder(names []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(d.dirname, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(d.dirname, orderFilename), []byte(strings.Join(names, "\n")), 0644); err != nil {
+		return fmt.Errorf("write order: %w", err)
+	}
+
+	return nil
+}
+
+// Checkpoint returns the checkpoint of the given buffer.
+func (d *Dir) Checkpoint(name string) *Checkpoint {
+	return New(filepath.Join(d.dirname, name))
+}
+
+// Remove deletes the checkpoint of the given buffer.
+func (d *Dir) Remove(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.Remove(filepath.Join(d.dirname, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove file: %w", err)
+	}
+
+	return nil
+}
+
+// Rename moves the checkpoint of a buffer to another name.
+func (d *Dir) Rename(oldName, newName string) error {
+	if err := ValidateName(newName); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	newFilename := filepath.Join(d.dirname, newName)
+
+	if _, err := os.Stat(newFilename); err == nil {
+		return fmt.Errorf("buffer %q already exists", newName)
+	}
+
+	if err := os.Rename(filepath.Join(d.dirname, oldName), newFilename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("rename file: %w", err)
+	}
+
+	return nil
+}
+
+// Import moves a checkpoint file written by a single-buffer version into the
+// directory as the given buffer. Nothing happens if the file does not exist or
+// the buffer already exists.
+func (d *Dir) Import(filename, name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	dst := filepath.Join(d.dirname, name)
+
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(d.dirname, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	if err := os.Rename(filename, dst); err != nil {
+		return fmt.Errorf("move file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDirNames(t *testing.T) {
+	t.Parallel()
+
+	d := NewDir(filepath.Join(t.TempDir(), "buffers"))
+
+	got, err := d.Names()
+	if err != nil {
+		t.Fatalf("want no error for missing directory, got: %s", err)
+	}
+
+	if len(got) != 0 {
+		t.Errorf("want no names, got: %v", got)
+	}
+
+	for _, name := range []string{"b", "a"} {
+		if err := d.Checkpoint(name).Save("select 1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err = d.Names()
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"a", "b"}, got); diff != "" {
+		t.Errorf("names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDirSaveOrder(t *testing.T) {
+	t.Parallel()
+
+	d := NewDir(t.TempDir())
+
+	for _, name := range []string{"query-10", "query-2", "a"} {
+		if err := d.Checkpoint(name).Save("select 1", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.SaveOrder([]string{"query-2", "removed", "query-10"}); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	got, err := d.Names()
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"query-2", "query-10", "a"}, got); diff != "" {
+		t.Errorf("names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDirRenameAndRemove(t *testing.T) {
+	t.Parallel()
+
+	d := NewDir(t.TempDir())
+
+	if err := d.Checkpoint("a").Save("select 1", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Checkpoint("b").Save("select 2", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Rename("a", "b"); err == nil {
+		t.Error("want error for existing name, got nil")
+	}
+
+	if err := d.Rename("a", "../c"); err == nil {
+		t.Error("want error for invalid name, got nil")
+	}
+
+	if err := d.Rename("a", "c"); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	got, err := d.Checkpoint("c").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "select 1" {
+		t.Errorf("want renamed checkpoint, got: %q", got)
+	}
+
+	if err := d.Remove("b"); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	names, err := d.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"c"}, names); diff != "" {
+		t.Errorf("names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDirImport(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	legacy := filepath.Join(base, "checkpoint")
+
+	if err := os.WriteFile(legacy, []byte("select 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDir(filepath.Join(base, "buffers"))
+
+	if err := d.Import(legacy, "main"); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+
+	got, err := d.Checkpoint("main").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "select 1" {
+		t.Errorf("want imported checkpoint, got: %q", got)
+	}
+
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("want the legacy file moved, got: %v", err)
+	}
+
+	if err := d.Import(legacy, "main"); err != nil {
+		t.Errorf("want no error for missing file, got: %s", err)
+	}
+}
@@ -0,0 +1,303 @@
+package googlesql
+
+import (
+	"strings"
+)
+
+// CompletionKind is the kind of names which can be completed at a position.
+type CompletionKind int
+
+const (
+	// CompleteNone means nothing is completed, e.g. in a string literal
+	CompleteNone CompletionKind = iota
+	// CompleteAny completes keywords, functions and columns of the tables
+	// referenced in the statement
+	CompleteAny
+	// CompleteTable completes datasets, or tables in the dataset given as
+	// Qualifier, e.g. after FROM
+	CompleteTable
+	// CompleteColumn completes columns of Table, e.g. after "alias."
+	CompleteColumn
+)
+
+// tableKeywords are keywords followed by a table name.
+var tableKeywords = newKeywordSet("FROM", "JOIN", "INTO", "UPDATE", "TABLE")
+
+// Completion describes what can be completed at a position of a query.
+type Completion struct {
+	Kind CompletionKind
+	// Prefix is the partial name before the position, which starts at Start
+	Prefix string
+	Start  int
+	// Qualifier is the dataset before "." for CompleteTable, e.g. "shop" in
+	// "FROM shop.or". It is empty if a dataset is being typed.
+	Qualifier string
+	// Table is the table referred by the qualifier for CompleteColumn. It is
+	// nil if the qualifier is unknown.
+	Table *TableRef
+	// Tables are the tables referenced in the statement.
+	Tables []TableRef
+}
+
+// TableRef is a table referenced in FROM or JOIN.
+type TableRef struct {
+	// Path is the table name split by dots without backticks, e.g.
+	// ["dataset", "table"]
+	Path  []string
+	Alias string
+}
+
+// Name returns the last element of the path, which is the table ID.
+func (r TableRef) Name() string {
+	return r.Path[len(r.Path)-1]
+}
+
+// CompletionAt returns what can be completed at the given byte offset in
+// query.
+func CompletionAt(query string, offset int) Completion {
+	start, end := 0, len(query)
+
+	// only the statement at the offset is analyzed
+	if stmt, ok := StatementAt(query, offset); ok {
+		start = min(stmt.Start, offset)
+		end = max(stmt.End, offset)
+
+		// the offset is in a new empty statement after the semicolon
+		if offset > stmt.End && strings.IndexByte(query[stmt.End:offset], ';') >= 0 {
+			start, end = offset, offset
+		}
+	}
+
+	text := query[start:end]
+	tokens := Tokenize(text)
+	pos := offset - start
+
+	c := Completion{
+		Kind:   CompleteAny,
+		Start:  offset,
+		Tables: tableRefs(text, tokens),
+	}
+
+	// i is the index of the token before the position
+	i := -1
+	for j, t := range tokens {
+		if t.Start >= pos {
+			break
+		}
+
+		i = j
+	}
+
+	if i < 0 {
+		return c
+	}
+
+	t := tokens[i]
+
+	if isInside(text, t, pos) {
+		return Completion{Kind: CompleteNone, Start: offset}
+	}
+
+	if isWord(t.Kind) && t.End >= pos {
+		c.Prefix = text[t.Start:pos]
+		c.Start = start + t.Start
+		i--
+	}
+
+	// the qualifier is the path right before the prefix, ending with "."
+	qualifier := ""
+
+	if i >= 0 && tokens[i].Kind == TokenOther && text[tokens[i].Start:tokens[i].End] == "." {
+		j := i
+		for j > 0 && tokens[j-1].End == tokens[j].Start && isPathPart(text, tokens[j-1]) {
+			j--
+		}
+
+		path, _ := parsePath(text, tokens[:i+1], j)
+		qualifier = strings.Join(path, ".")
+
+		i = j - 1
+	}
+
+	// the keyword before the name decides what it is
+	for i >= 0 && (tokens[i].Kind == TokenSpace || tokens[i].Kind == TokenComment) {
+		i--
+	}
+
+	if i >= 0 && tokens[i].Kind == TokenKeyword && isTableKeyword(text[tokens[i].Start:tokens[i].End]) {
+		c.Kind = CompleteTable
+		c.Qualifier = qualifier
+
+		return c
+	}
+
+	if qualifier != "" {
+		c.Kind = CompleteColumn
+		c.Table = resolveTable(c.Tables, qualifier)
+	}
+
+	return c
+}
+
+// resolveTable finds the table referred by the qualifier, which is an alias, a
+// table ID or a table path.
+func resolveTable(refs []TableRef, qualifier string) *TableRef {
+	if strings.Contains(qualifier, ".") {
+		return &TableRef{Path: strings.Split(qualifier, ".")}
+	}
+
+	for _, r := range refs {
+		if strings.EqualFold(r.Alias, qualifier) || r.Alias == "" && strings.EqualFold(r.Name(), qualifier) {
+			rr := r
+			return &rr
+		}
+	}
+
+	return nil
+}
+
+// isInside reports whether pos is inside the string literal, quoted identifier
+// or comment t, where nothing is completed.
+func isInside(text string, t Token, pos int) bool {
+	s := text[t.Start:t.End]
+
+	switch t.Kind {
+	case TokenComment:
+		return pos < t.End || !strings.HasPrefix(s, "/*") || !strings.HasSuffix(s, "*/") || len(s) < 4
+
+	case TokenString, TokenQuotedIdentifier:
+		return pos < t.End || !isClosed(s)
+
+	default:
+		return false
+	}
+}
+
+// isClosed reports whether the quoted literal s has the closing quote.
+func isClosed(s string) bool {
+	body := strings.TrimLeft(s, "rRbB")
+	if body == "" {
+		return false
+	}
+
+	delim := body[:1]
+	if len(body) >= 6 && strings.HasPrefix(body, strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+
+	return len(body) >= 2*len(delim) && strings.HasSuffix(body, delim)
+}
+
+// tableRefs returns tables referenced after FROM and JOIN.
+func tableRefs(text string, tokens []Token) []TableRef {
+	refs := []TableRef{}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind != TokenKeyword {
+			continue
+		}
+
+		word := strings.ToUpper(text[t.Start:t.End])
+		if word != "FROM" && word != "JOIN" {
+			continue
+		}
+
+		// FROM may be followed by tables separated by commas
+		for {
+			// e.g. UNNEST(...) and subqueries are not tables
+			j := nextSignificant(tokens, i+1)
+			if j >= len(tokens) || !isPathPart(text, tokens[j]) || tokens[j].Kind == TokenKeyword && IsReservedKeyword(strings.ToUpper(text[tokens[j].Start:tokens[j].End])) {
+				break
+			}
+
+			path, k := parsePath(text, tokens, j)
+			if len(path) == 0 {
+				break
+			}
+
+			ref := TableRef{Path: path}
+
+			k = nextSignificant(tokens, k)
+			if k < len(tokens) && tokens[k].Kind == TokenKeyword && strings.EqualFold(text[tokens[k].Start:tokens[k].End], "AS") {
+				k = nextSignificant(tokens, k+1)
+			}
+
+			if k < len(tokens) && tokens[k].Kind == TokenIdentifier {
+				ref.Alias = text[tokens[k].Start:tokens[k].End]
+				k++
+			}
+
+			refs = append(refs, ref)
+			i = k - 1
+
+			k = nextSignificant(tokens, k)
+			if word != "FROM" || k >= len(tokens) || text[tokens[k].Start:tokens[k].End] != "," {
+				break
+			}
+
+			i = k
+		}
+	}
+
+	return refs
+}
+
+// parsePath parses a dotted name starting at tokens[i], e.g. my-project.shop.`orders`,
+// and returns its elements and the index of the token after it.
+func parsePath(text string, tokens []Token, i int) ([]string, int) {
+	var b strings.Builder
+
+	j := i
+	for ; j < len(tokens); j++ {
+		t := tokens[j]
+		if j > i && t.Start != tokens[j-1].End || !isPathPart(text, t) {
+			break
+		}
+
+		s := text[t.Start:t.End]
+		if t.Kind == TokenQuotedIdentifier {
+			s = strings.Trim(s, "`")
+		}
+
+		b.WriteString(s)
+	}
+
+	raw := strings.Trim(b.String(), ".")
+	if raw == "" {
+		return nil, j
+	}
+
+	return strings.Split(raw, "."), j
+}
+
+// isPathPart reports whether t can be a part of a table path.
+func isPathPart(text string, t Token) bool {
+	if isWord(t.Kind) || t.Kind == TokenQuotedIdentifier {
+		return true
+	}
+
+	// project IDs may contain hyphens
+	s := text[t.Start:t.End]
+
+	return t.Kind == TokenOther && (s == "." || s == "-")
+}
+
+func isWord(k TokenKind) bool {
+	return k == TokenIdentifier || k == TokenKeyword || k == TokenFunction
+}
+
+func isTableKeyword(word string) bool {
+	_, ok := tableKeywords[strings.ToUpper(word)]
+	return ok
+}
+
+// nextSignificant returns the index of the first token at or after i which is
+// neither a space nor a comment.
+func nextSignificant(tokens []Token, i int) int {
+	for i < len(tokens) && (tokens[i].Kind == TokenSpace || tokens[i].Kind == TokenComment) {
+		i++
+	}
+
+	return i
+}
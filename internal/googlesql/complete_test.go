@@ -0,0 +1,123 @@
+package googlesql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompletionAt(t *testing.T) {
+	t.Parallel()
+
+	// "|" marks the position
+	testcases := map[string]struct {
+		query string
+		want  Completion
+	}{
+		"empty": {
+			query: "|",
+			want:  Completion{Kind: CompleteAny, Tables: []TableRef{}},
+		},
+		"keyword": {
+			query: "sel|",
+			want:  Completion{Kind: CompleteAny, Prefix: "sel", Tables: []TableRef{}},
+		},
+		"after FROM": {
+			query: "select * from |",
+			want:  Completion{Kind: CompleteTable, Start: 14, Tables: []TableRef{}},
+		},
+		"dataset after FROM": {
+			query: "select * from sh|",
+			want: Completion{
+				Kind:   CompleteTable,
+				Prefix: "sh",
+				Start:  14,
+				Tables: []TableRef{{Path: []string{"sh"}}},
+			},
+		},
+		"table in dataset": {
+			query: "select * from shop.or| join logs.access as a",
+			want: Completion{
+				Kind:      CompleteTable,
+				Prefix:    "or",
+				Start:     19,
+				Qualifier: "shop",
+				Tables: []TableRef{
+					{Path: []string{"shop", "or"}},
+					{Path: []string{"logs", "access"}, Alias: "a"},
+				},
+			},
+		},
+		"columns of alias": {
+			query: "select o.| from shop.orders o, `my-project.shop.users` as u",
+			want: Completion{
+				Kind:  CompleteColumn,
+				Start: 9,
+				Table: &TableRef{Path: []string{"shop", "orders"}, Alias: "o"},
+				Tables: []TableRef{
+					{Path: []string{"shop", "orders"}, Alias: "o"},
+					{Path: []string{"my-project", "shop", "users"}, Alias: "u"},
+				},
+			},
+		},
+		"columns of table without alias": {
+			query: "select * from shop.orders where Orders.st|",
+			want: Completion{
+				Kind:   CompleteColumn,
+				Prefix: "st",
+				Start:  39,
+				Table:  &TableRef{Path: []string{"shop", "orders"}},
+				Tables: []TableRef{{Path: []string{"shop", "orders"}}},
+			},
+		},
+		"unknown qualifier": {
+			query: "select x.|",
+			want:  Completion{Kind: CompleteColumn, Start: 9, Tables: []TableRef{}},
+		},
+		"only the current statement": {
+			query: "select * from a.b x; select x.|",
+			want:  Completion{Kind: CompleteColumn, Start: 30, Tables: []TableRef{}},
+		},
+		"new statement": {
+			query: "select * from a.b; |",
+			want:  Completion{Kind: CompleteAny, Start: 19, Tables: []TableRef{}},
+		},
+		"in string": {
+			query: "select 'fr|'",
+			want:  Completion{Kind: CompleteNone, Start: 10},
+		},
+		"in unterminated string": {
+			query: "select 'fr|",
+			want:  Completion{Kind: CompleteNone, Start: 10},
+		},
+		"after string": {
+			query: "select 'a' |",
+			want:  Completion{Kind: CompleteAny, Start: 11, Tables: []TableRef{}},
+		},
+		"in line comment": {
+			query: "select 1 -- fr|",
+			want:  Completion{Kind: CompleteNone, Start: 14},
+		},
+		"UNNEST is not a table": {
+			query: "select | from unnest([1]) as x",
+			want:  Completion{Kind: CompleteAny, Start: 7, Tables: []TableRef{}},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			offset := strings.Index(tc.query, "|")
+			query := strings.Replace(tc.query, "|", "", 1)
+
+			got := CompletionAt(query, offset)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
+	}
+}
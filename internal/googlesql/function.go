@@ -0,0 +1,51 @@
+package googlesql
+
+// functions are commonly used built-in functions offered for completion.
+// https://cloud.google.com/bigquery/docs/reference/standard-sql/functions-all
+var functions = []string{
+	// aggregate
+	"ANY_VALUE", "APPROX_COUNT_DISTINCT", "APPROX_QUANTILES", "APPROX_TOP_COUNT",
+	"ARRAY_AGG", "ARRAY_CONCAT_AGG", "AVG", "COUNT", "COUNTIF", "LOGICAL_AND",
+	"LOGICAL_OR", "MAX", "MAX_BY", "MIN", "MIN_BY", "STRING_AGG", "SUM",
+	"STDDEV", "VARIANCE",
+	// window
+	"CUME_DIST", "DENSE_RANK", "FIRST_VALUE", "LAG", "LAST_VALUE", "LEAD",
+	"NTH_VALUE", "NTILE", "PERCENT_RANK", "RANK", "ROW_NUMBER",
+	// conditional
+	"COALESCE", "IFNULL", "NULLIF",
+	// conversion
+	"SAFE_CAST", "PARSE_DATE", "PARSE_DATETIME", "PARSE_TIMESTAMP",
+	"FORMAT_DATE", "FORMAT_DATETIME", "FORMAT_TIMESTAMP",
+	// date and time
+	"CURRENT_DATE", "CURRENT_DATETIME", "CURRENT_TIME", "CURRENT_TIMESTAMP",
+	"DATE", "DATE_ADD", "DATE_DIFF", "DATE_SUB", "DATE_TRUNC", "DATETIME",
+	"DATETIME_ADD", "DATETIME_DIFF", "DATETIME_SUB", "DATETIME_TRUNC",
+	"TIMESTAMP", "TIMESTAMP_ADD", "TIMESTAMP_DIFF", "TIMESTAMP_MICROS",
+	"TIMESTAMP_MILLIS", "TIMESTAMP_SECONDS", "TIMESTAMP_SUB", "TIMESTAMP_TRUNC",
+	"UNIX_MICROS", "UNIX_MILLIS", "UNIX_SECONDS", "GENERATE_DATE_ARRAY",
+	"GENERATE_TIMESTAMP_ARRAY", "LAST_DAY",
+	// string
+	"CONCAT", "CONTAINS_SUBSTR", "ENDS_WITH", "FORMAT", "INSTR", "LENGTH",
+	"LOWER", "LPAD", "LTRIM", "REGEXP_CONTAINS", "REGEXP_EXTRACT",
+	"REGEXP_EXTRACT_ALL", "REGEXP_REPLACE", "REPEAT", "REPLACE", "REVERSE",
+	"RPAD", "RTRIM", "SPLIT", "STARTS_WITH", "STRPOS", "SUBSTR", "TO_BASE64",
+	"FROM_BASE64", "TRIM", "UPPER",
+	// math
+	"ABS", "CEIL", "DIV", "EXP", "FLOOR", "GREATEST", "LEAST", "LN", "LOG",
+	"MOD", "POW", "RAND", "ROUND", "SAFE_DIVIDE", "SIGN", "SQRT", "TRUNC",
+	// array
+	"ARRAY_CONCAT", "ARRAY_LENGTH", "ARRAY_REVERSE", "ARRAY_TO_STRING",
+	"GENERATE_ARRAY",
+	// JSON
+	"JSON_EXTRACT", "JSON_EXTRACT_SCALAR", "JSON_QUERY", "JSON_QUERY_ARRAY",
+	"JSON_VALUE", "JSON_VALUE_ARRAY", "PARSE_JSON", "TO_JSON", "TO_JSON_STRING",
+	// hash
+	"FARM_FINGERPRINT", "GENERATE_UUID", "MD5", "SHA256",
+	// geography
+	"ST_DISTANCE", "ST_GEOGPOINT", "ST_GEOGFROMTEXT",
+}
+
+// Functions returns names of commonly used built-in functions.
+func Functions() []string {
+	return append([]string{}, functions...)
+}
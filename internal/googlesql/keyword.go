@@ -0,0 +1,86 @@
+package googlesql
+
+import (
+	"maps"
+	"slices"
+)
+
+// reservedKeywords are the reserved keywords of GoogleSQL, which cannot be
+// used as identifiers without backticks.
+// https://cloud.google.com/bigquery/docs/reference/standard-sql/lexical#reserved_keywords
+var reservedKeywords = newKeywordSet(
+	"ALL", "AND", "ANY", "ARRAY", "AS", "ASC", "ASSERT_ROWS_MODIFIED", "AT",
+	"BETWEEN", "BY", "CASE", "CAST", "COLLATE", "CONTAINS", "CREATE", "CROSS",
+	"CUBE", "CURRENT", "DEFAULT", "DEFINE", "DESC", "DISTINCT", "ELSE", "END",
+	"ENUM", "ESCAPE", "EXCEPT", "EXCLUDE", "EXISTS", "EXTRACT", "FALSE",
+	"FETCH", "FOLLOWING", "FOR", "FROM", "FULL", "GROUP", "GROUPING", "GROUPS",
+	"HASH", "HAVING", "IF", "IGNORE", "IN", "INNER", "INTERSECT", "INTERVAL",
+	"INTO", "IS", "JOIN", "LATERAL", "LEFT", "LIKE", "LIMIT", "LOOKUP", "MERGE",
+	"NATURAL", "NEW", "NO", "NOT", "NULL", "NULLS", "OF", "ON", "OR", "ORDER",
+	"OUTER", "OVER", "PARTITION", "PRECEDING", "PROTO", "QUALIFY", "RANGE",
+	"RECURSIVE", "RESPECT", "RIGHT", "ROLLUP", "ROWS", "SELECT", "SET", "SOME",
+	"STRUCT", "TABLESAMPLE", "THEN", "TO", "TREAT", "TRUE", "UNBOUNDED",
+	"UNION", "UNNEST", "USING", "WHEN", "WHERE", "WINDOW", "WITH", "WITHIN",
+)
+
+// nonReservedKeywords are commonly used keywords which may also be used as
+// identifiers or function names, e.g. DATE.
+var nonReservedKeywords = newKeywordSet(
+	// statements
+	"ALTER", "BEGIN", "CALL", "COMMIT", "DECLARE", "DELETE", "DROP", "EXECUTE",
+	"IMMEDIATE", "INSERT", "REPLACE", "ROLLBACK", "TRANSACTION", "TRUNCATE",
+	"UPDATE", "VALUES", "EXPORT", "LOAD", "GRANT", "REVOKE",
+	// objects
+	"TABLE", "VIEW", "MATERIALIZED", "FUNCTION", "PROCEDURE", "SCHEMA",
+	"TEMP", "TEMPORARY", "OPTIONS", "CLUSTER", "EXTERNAL", "SNAPSHOT", "CLONE",
+	"RETURNS",
+	// scripting
+	"BREAK", "CONTINUE", "DO", "ELSEIF", "EXCEPTION", "ITERATE",
+	"LEAVE", "LOOP", "RAISE", "REPEAT", "RETURN", "UNTIL", "WHILE",
+	// clauses
+	"MATCHED", "OFFSET", "PIVOT", "UNPIVOT", "SYSTEM_TIME",
+	// types
+	"BIGNUMERIC", "BIGDECIMAL", "BOOL", "BYTES", "DATE", "DATETIME", "DECIMAL",
+	"FLOAT64", "GEOGRAPHY", "INT64", "JSON", "NUMERIC", "STRING", "TIME",
+	"TIMESTAMP",
+)
+
+type keywordSet map[string]struct{}
+
+func newKeywordSet(keywords ...string) keywordSet {
+	s := make(keywordSet, len(keywords))
+
+	for _, k := range keywords {
+		s[k] = struct{}{}
+	}
+
+	return s
+}
+
+// IsReservedKeyword reports whether the upper-cased word is a reserved keyword.
+func IsReservedKeyword(word string) bool {
+	_, ok := reservedKeywords[word]
+	return ok
+}
+
+// IsKeyword reports whether the upper-cased word is a keyword, reserved or not.
+func IsKeyword(word string) bool {
+	if IsReservedKeyword(word) {
+		return true
+	}
+
+	_, ok := nonReservedKeywords[word]
+
+	return ok
+}
+
+// Keywords returns reserved and commonly used non-reserved keywords in
+// alphabetical order.
+func Keywords() []string {
+	ks := slices.Collect(maps.Keys(reservedKeywords))
+	ks = slices.AppendSeq(ks, maps.Keys(nonReservedKeywords))
+
+	slices.Sort(ks)
+
+	return ks
+}
@@ -0,0 +1,37 @@
+package googlesql
+
+import (
+	"strings"
+)
+
+// Parameters returns names of the named query parameters referenced in query,
+// without "@", in the order of their first appearance. Names are compared
+// case-insensitively as BigQuery does. System variables such as @@project_id
+// are not parameters.
+func Parameters(query string) []string {
+	names := []string{}
+	seen := map[string]struct{}{}
+
+	for _, t := range Tokenize(query) {
+		if t.Kind != TokenParameter {
+			continue
+		}
+
+		s := t.Text(query)
+		if strings.HasPrefix(s, "@@") {
+			continue
+		}
+
+		name := s[1:]
+
+		key := strings.ToLower(name)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		names = append(names, name)
+	}
+
+	return names
+}
@@ -0,0 +1,48 @@
+package googlesql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParameters(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		query string
+		want  []string
+	}{
+		"no parameters": {
+			query: "select 1",
+			want:  []string{},
+		},
+		"in order of appearance": {
+			query: "select * from t where d >= @start_date and user_id = @user_id and d < @end_date",
+			want:  []string{"start_date", "user_id", "end_date"},
+		},
+		"duplicates": {
+			query: "select @a, @b, @A, @a",
+			want:  []string{"a", "b"},
+		},
+		"system variables": {
+			query: "select @@project_id, @x",
+			want:  []string{"x"},
+		},
+		"strings and comments": {
+			query: "select '@a', `@b` -- @c\n, @d /* @e */",
+			want:  []string{"d"},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.want, Parameters(tc.query)); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
+	}
+}
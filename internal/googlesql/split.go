@@ -0,0 +1,277 @@
+// Package googlesql provides lexical helpers for GoogleSQL, the SQL dialect of
+// BigQuery.
+package googlesql
+
+import (
+	"strings"
+)
+
+// Statement is a part of a query text terminated by a semicolon.
+type Statement struct {
+	// Text is the statement without surrounding spaces and the semicolon
+	Text string
+	// Start and End are byte offsets of Text in the whole query
+	Start int
+	End   int
+}
+
+// Split splits query into statements by semicolons outside of string
+// literals, quoted identifiers and comments. Statements without code, e.g. only
+// with comments, are dropped. Blocks of scripts such as BEGIN ... END and
+// IF ... END IF, including bodies of procedures, are kept in one statement.
+func Split(query string) []Statement {
+	stmts := []Statement{}
+
+	segStart := 0
+	hasCode := false
+
+	flush := func(end int) {
+		if hasCode {
+			stmts = append(stmts, newStatement(query, segStart, end))
+		}
+
+		segStart = end + 1
+		hasCode = false
+	}
+
+	tokens := significantTokens(Tokenize(query))
+
+	// blocks are kinds of blocks enclosing the current token, innermost last
+	blocks := []string{}
+	// stmtStart is true where a statement of a script can start
+	stmtStart := true
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		if t.Kind == TokenOther && query[t.Start] == ';' {
+			if len(blocks) == 0 {
+				flush(t.Start)
+			}
+
+			stmtStart = true
+
+			continue
+		}
+
+		hasCode = true
+
+		word := blockWord(query, t)
+		next := ""
+		if i+1 < len(tokens) {
+			next = blockWord(query, tokens[i+1])
+			if next == "" {
+				next = tokens[i+1].Text(query)
+			}
+		}
+
+		inner := ""
+		if len(blocks) > 0 {
+			inner = blocks[len(blocks)-1]
+		}
+
+		switch {
+		case word == "BEGIN":
+			// BEGIN and BEGIN TRANSACTION start a transaction
+			if next != ";" && next != "TRANSACTION" && next != "" {
+				blocks = append(blocks, word)
+				stmtStart = true
+
+				continue
+			}
+
+		case word == "END":
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+
+			// END IF, END LOOP etc.
+			if closesBlock(next) {
+				i++
+			}
+
+		case word == "CASE":
+			// CASE at the start of a statement ends with END CASE, while CASE
+			// in an expression ends with END
+			if stmtStart {
+				blocks = append(blocks, word)
+			} else {
+				blocks = append(blocks, caseExpression)
+			}
+
+		case stmtStart && (word == "IF" || word == "WHILE" || word == "FOR"):
+			blocks = append(blocks, word)
+
+		case stmtStart && (word == "LOOP" || word == "REPEAT"):
+			blocks = append(blocks, word)
+
+			continue
+
+		case word == "THEN" || word == "ELSE":
+			stmtStart = inner != "" && inner != caseExpression
+
+			continue
+
+		case word == "DO":
+			stmtStart = inner == "WHILE" || inner == "FOR"
+
+			continue
+
+		case stmtStart && word != "" && next == ":":
+			// label of a block, e.g. "outer: LOOP"
+			i++
+
+			continue
+		}
+
+		stmtStart = false
+	}
+
+	flush(len(query))
+
+	return stmts
+}
+
+// caseExpression is the kind of blocks of CASE expressions, which are closed
+// by END like blocks of statements.
+const caseExpression = "CASE expression"
+
+// significantTokens drops spaces and comments.
+func significantTokens(tokens []Token) []Token {
+	ts := make([]Token, 0, len(tokens))
+
+	for _, t := range tokens {
+		if t.Kind != TokenSpace && t.Kind != TokenComment {
+			ts = append(ts, t)
+		}
+	}
+
+	return ts
+}
+
+// blockWord returns the upper-cased word of t, or "" if t is not a word.
+func blockWord(query string, t Token) string {
+	if !isWord(t.Kind) {
+		return ""
+	}
+
+	return strings.ToUpper(t.Text(query))
+}
+
+// closesBlock reports whether word follows END to close a block of the kind.
+func closesBlock(word string) bool {
+	switch word {
+	case "IF", "WHILE", "LOOP", "REPEAT", "FOR", "CASE":
+		return true
+	}
+
+	return false
+}
+
+// StatementAt returns the statement at the given byte offset in query. An
+// offset between statements, e.g. right after a semicolon, belongs to the
+// preceding one.
+func StatementAt(query string, offset int) (Statement, bool) {
+	stmts := Split(query)
+	if len(stmts) == 0 {
+		return Statement{}, false
+	}
+
+	for i := len(stmts) - 1; i > 0; i-- {
+		if offset >= stmts[i].Start {
+			return stmts[i], true
+		}
+	}
+
+	return stmts[0], true
+}
+
+// newStatement trims spaces around query[start:end].
+func newStatement(query string, start, end int) Statement {
+	s := query[start:end]
+
+	trimmed := strings.TrimLeft(s, " \t\r\n")
+	start += len(s) - len(trimmed)
+
+	trimmed = strings.TrimRight(trimmed, " \t\r\n")
+
+	return Statement{
+		Text:  trimmed,
+		Start: start,
+		End:   start + len(trimmed),
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// skipLineComment returns the offset of the line break ending the comment at i.
+func skipLineComment(query string, i int) int {
+	if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+		return i + n
+	}
+
+	return len(query)
+}
+
+// skipBlockComment returns the offset right after the comment at i.
+func skipBlockComment(query string, i int) int {
+	if n := strings.Index(query[i+2:], "*/"); n >= 0 {
+		return i + 2 + n + 2
+	}
+
+	return len(query)
+}
+
+// quotedLen returns the length of a string literal, bytes literal or quoted
+// identifier at i, including its prefix and quotes. It returns 0 if there is
+// none. An unterminated one spans to the end of query.
+func quotedLen(query string, i int) int {
+	// string literals may have prefixes of r (raw) and b (bytes), e.g. r'...',
+	// B"...", rb'''...'''
+	j := i
+	for j < len(query) && j-i < 2 && strings.IndexByte("rRbB", query[j]) >= 0 {
+		j++
+	}
+
+	if j >= len(query) {
+		return 0
+	}
+
+	q := query[j]
+
+	switch {
+	case q == '\'' || q == '"':
+	case q == '`' && j == i:
+	default:
+		return 0
+	}
+
+	delim := string(q)
+	if q != '`' && strings.HasPrefix(query[j:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+
+	k := j + len(delim)
+
+	for k < len(query) {
+		switch {
+		case query[k] == '\\':
+			// escapes are kept as is in raw strings but still cannot end them
+			k += 2
+
+		case strings.HasPrefix(query[k:], delim):
+			return k + len(delim) - i
+
+		case query[k] == '\n' && len(delim) == 1 && q != '`':
+			// a single-quoted string cannot span lines
+			return k - i
+
+		default:
+			k++
+		}
+	}
+
+	return len(query) - i
+}
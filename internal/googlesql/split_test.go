@@ -0,0 +1,159 @@
+package googlesql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		query string
+		want  []string
+	}{
+		"single statement without semicolon": {
+			query: "select 1",
+			want:  []string{"select 1"},
+		},
+		"multiple statements": {
+			query: "select 1;\nselect 2;\n\n  select 3  ",
+			want:  []string{"select 1", "select 2", "select 3"},
+		},
+		"semicolons in strings": {
+			query: `select ';', "a;b", '''x;
+y''', r'\';'; select 2`,
+			want: []string{`select ';', "a;b", '''x;
+y''', r'\';'`, "select 2"},
+		},
+		"semicolons in backtick identifiers": {
+			query: "select * from `p.d;t`; select 2",
+			want:  []string{"select * from `p.d;t`", "select 2"},
+		},
+		"semicolons in comments": {
+			query: "select 1 -- a; b\n, 2 # c; d\n, /* e; f */ 3; select 4",
+			want:  []string{"select 1 -- a; b\n, 2 # c; d\n, /* e; f */ 3", "select 4"},
+		},
+		"statements only with comments are dropped": {
+			query: "select 1;\n-- select 2;\n;  ;/* select 3 */",
+			want:  []string{"select 1"},
+		},
+		"begin end block": {
+			query: "begin\n  select 1;\n  select 2;\nend;\nselect 3",
+			want:  []string{"begin\n  select 1;\n  select 2;\nend", "select 3"},
+		},
+		"nested blocks": {
+			query: "declare x int64 default 1;\nif x > 0 then\n  while x < 3 do\n    set x = x + 1;\n  end while;\nelseif x < 0 then\n  loop\n    leave;\n  end loop;\nelse\n  select if(x = 0, 'a', 'b');\nend if;\nselect x",
+			want: []string{
+				"declare x int64 default 1",
+				"if x > 0 then\n  while x < 3 do\n    set x = x + 1;\n  end while;\nelseif x < 0 then\n  loop\n    leave;\n  end loop;\nelse\n  select if(x = 0, 'a', 'b');\nend if",
+				"select x",
+			},
+		},
+		"case expressions in a block": {
+			query: "begin\n  select case when true then 1 else 2 end;\n  select 3;\nend; select 4",
+			want:  []string{"begin\n  select case when true then 1 else 2 end;\n  select 3;\nend", "select 4"},
+		},
+		"case statement": {
+			query: "case\n  when true then select 1;\n  else select 2;\nend case; select 3",
+			want:  []string{"case\n  when true then select 1;\n  else select 2;\nend case", "select 3"},
+		},
+		"procedure body": {
+			query: "create procedure d.p()\nbegin\n  select 1;\nexception when error then\n  select 2;\nend;\ncall d.p()",
+			want:  []string{"create procedure d.p()\nbegin\n  select 1;\nexception when error then\n  select 2;\nend", "call d.p()"},
+		},
+		"labeled loop": {
+			query: "retry: repeat\n  set x = x + 1;\n  until x > 3\nend repeat retry; select x",
+			want:  []string{"retry: repeat\n  set x = x + 1;\n  until x > 3\nend repeat retry", "select x"},
+		},
+		"transactions are not blocks": {
+			query: "begin transaction; insert d.t values (1); commit transaction; begin; select 1",
+			want:  []string{"begin transaction", "insert d.t values (1)", "commit transaction", "begin", "select 1"},
+		},
+		"if exists is not a block": {
+			query: "drop table if exists d.t; select 1",
+			want:  []string{"drop table if exists d.t", "select 1"},
+		},
+		"unterminated string": {
+			query: "select 'a;b",
+			want:  []string{"select 'a;b"},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := []string{}
+			for _, s := range Split(tc.query) {
+				if s.Text != tc.query[s.Start:s.End] {
+					t.Errorf("want Text at [%d:%d], got: %q", s.Start, s.End, s.Text)
+				}
+
+				got = append(got, s.Text)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	t.Parallel()
+
+	query := "\nselect 1;\n\nselect 'a;b';  \nselect 3"
+
+	testcases := map[string]struct {
+		offset int
+		want   string
+	}{
+		"before the first statement": {
+			offset: 0,
+			want:   "select 1",
+		},
+		"in the first statement": {
+			offset: 3,
+			want:   "select 1",
+		},
+		"right after a semicolon": {
+			offset: 10,
+			want:   "select 1",
+		},
+		"in a string": {
+			offset: 21,
+			want:   "select 'a;b'",
+		},
+		"at the end": {
+			offset: len(query),
+			want:   "select 3",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := StatementAt(query, tc.offset)
+			if !ok {
+				t.Fatal("want a statement, got none")
+			}
+
+			if got.Text != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got.Text)
+			}
+		})
+	}
+}
+
+func TestStatementAt_empty(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := StatementAt(" -- nothing\n", 0); ok {
+		t.Error("want no statement")
+	}
+}
@@ -0,0 +1,221 @@
+package googlesql
+
+import (
+	"strings"
+)
+
+// TokenKind is the lexical category of a token.
+type TokenKind int
+
+const (
+	// TokenOther is an operator or punctuation, e.g. "(", ",", ";", ">="
+	TokenOther TokenKind = iota
+	TokenSpace
+	TokenComment
+	TokenKeyword
+	// TokenFunction is a name followed by "(", e.g. COUNT in "COUNT(*)"
+	TokenFunction
+	TokenIdentifier
+	// TokenQuotedIdentifier is an identifier enclosed in backticks
+	TokenQuotedIdentifier
+	// TokenString is a string or bytes literal including its prefix and quotes
+	TokenString
+	TokenNumber
+	// TokenParameter is a query parameter or a system variable, e.g. @name,
+	// @@project_id
+	TokenParameter
+)
+
+// Token is a lexical unit of a query.
+type Token struct {
+	Kind TokenKind
+	// Start and End are byte offsets of the token in the query
+	Start int
+	End   int
+}
+
+// Text returns the token in query.
+func (t Token) Text(query string) string {
+	return query[t.Start:t.End]
+}
+
+// Tokenize splits query into tokens. Every byte of query belongs to exactly
+// one token, so that the tokens can be used to render the whole query. An
+// unterminated literal or comment spans to the end of query.
+func Tokenize(query string) []Token {
+	tokens := make([]Token, 0, len(query)/4)
+
+	// afterDot is true right after ".", where a keyword is a field or table
+	// name
+	afterDot := false
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		kind := TokenOther
+		end := i + 1
+
+		quoted := 0
+		if strings.IndexByte("'\"`rRbB", c) >= 0 {
+			quoted = quotedLen(query, i)
+		}
+
+		switch {
+		case isSpace(c):
+			kind = TokenSpace
+			for end < len(query) && isSpace(query[end]) {
+				end++
+			}
+
+		case c == '#' || strings.HasPrefix(query[i:], "--"):
+			kind = TokenComment
+			end = skipLineComment(query, i)
+
+		case strings.HasPrefix(query[i:], "/*"):
+			kind = TokenComment
+			end = skipBlockComment(query, i)
+
+		case quoted > 0:
+			kind = TokenString
+			if c == '`' {
+				kind = TokenQuotedIdentifier
+			}
+
+			end = i + quoted
+
+		case isDigit(c) || c == '.' && i+1 < len(query) && isDigit(query[i+1]):
+			kind = TokenNumber
+			end = numberEnd(query, i)
+
+		case c == '@':
+			j := i + 1
+			if j < len(query) && query[j] == '@' {
+				j++
+			}
+
+			if j < len(query) && isWordStart(query[j]) {
+				kind = TokenParameter
+				end = wordEnd(query, j)
+			}
+
+		case isWordStart(c):
+			end = wordEnd(query, i)
+			kind = wordKind(query, i, end, afterDot)
+
+		default:
+			end = operatorEnd(query, i)
+		}
+
+		afterDot = kind == TokenOther && end == i+1 && c == '.'
+
+		tokens = append(tokens, Token{
+			Kind:  kind,
+			Start: i,
+			End:   end,
+		})
+
+		i = end
+	}
+
+	return tokens
+}
+
+// wordKind classifies the word query[start:end]. Reserved keywords are always
+// keywords, while other names followed by "(" are functions, including
+// non-reserved keywords such as REPLACE and DATE.
+func wordKind(query string, start, end int, afterDot bool) TokenKind {
+	if afterDot {
+		return TokenIdentifier
+	}
+
+	word := strings.ToUpper(query[start:end])
+
+	if IsReservedKeyword(word) {
+		return TokenKeyword
+	}
+
+	j := end
+	for j < len(query) && isSpace(query[j]) {
+		j++
+	}
+
+	if j < len(query) && query[j] == '(' {
+		return TokenFunction
+	}
+
+	if IsKeyword(word) {
+		return TokenKeyword
+	}
+
+	return TokenIdentifier
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	// non-ASCII bytes are part of unquoted words so that a multibyte character
+	// is never split
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || isDigit(c)
+}
+
+// wordEnd returns the offset right after the word at i.
+func wordEnd(query string, i int) int {
+	for i < len(query) && isWordPart(query[i]) {
+		i++
+	}
+
+	return i
+}
+
+// numberEnd returns the offset right after the number literal at i, e.g. 42,
+// 1.5e-3, .5 or 0x1F.
+func numberEnd(query string, i int) int {
+	if strings.HasPrefix(query[i:], "0x") || strings.HasPrefix(query[i:], "0X") {
+		i += 2
+		for i < len(query) && strings.IndexByte("0123456789abcdefABCDEF", query[i]) >= 0 {
+			i++
+		}
+
+		return i
+	}
+
+	for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+		i++
+	}
+
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+
+		if j < len(query) && isDigit(query[j]) {
+			i = j
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+		}
+	}
+
+	return i
+}
+
+// operators are operators longer than a byte.
+var operators = []string{"<=", ">=", "<>", "!=", "||", "<<", ">>", "=>"}
+
+// operatorEnd returns the offset right after the operator or punctuation at i.
+func operatorEnd(query string, i int) int {
+	for _, op := range operators {
+		if strings.HasPrefix(query[i:], op) {
+			return i + len(op)
+		}
+	}
+
+	return i + 1
+}
@@ -0,0 +1,159 @@
+package googlesql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type tokenText struct {
+	Kind TokenKind
+	Text string
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		query string
+		want  []tokenText
+	}{
+		"keywords, functions and identifiers": {
+			query: "SELECT count(*) AS n FROM t",
+			want: []tokenText{
+				{TokenKeyword, "SELECT"},
+				{TokenFunction, "count"},
+				{TokenOther, "("},
+				{TokenOther, "*"},
+				{TokenOther, ")"},
+				{TokenKeyword, "AS"},
+				{TokenIdentifier, "n"},
+				{TokenKeyword, "FROM"},
+				{TokenIdentifier, "t"},
+			},
+		},
+		"keywords are case-insensitive": {
+			query: "select Distinct x",
+			want: []tokenText{
+				{TokenKeyword, "select"},
+				{TokenKeyword, "Distinct"},
+				{TokenIdentifier, "x"},
+			},
+		},
+		"non-reserved keywords followed by parenthesis are functions": {
+			query: "date(ts), replace (s, 'a', 'b'), date",
+			want: []tokenText{
+				{TokenFunction, "date"},
+				{TokenOther, "("},
+				{TokenIdentifier, "ts"},
+				{TokenOther, ")"},
+				{TokenOther, ","},
+				{TokenFunction, "replace"},
+				{TokenOther, "("},
+				{TokenIdentifier, "s"},
+				{TokenOther, ","},
+				{TokenString, "'a'"},
+				{TokenOther, ","},
+				{TokenString, "'b'"},
+				{TokenOther, ")"},
+				{TokenOther, ","},
+				{TokenKeyword, "date"},
+			},
+		},
+		"keywords after dot are identifiers": {
+			query: "t.select, t.`from`",
+			want: []tokenText{
+				{TokenIdentifier, "t"},
+				{TokenOther, "."},
+				{TokenIdentifier, "select"},
+				{TokenOther, ","},
+				{TokenIdentifier, "t"},
+				{TokenOther, "."},
+				{TokenQuotedIdentifier, "`from`"},
+			},
+		},
+		"literals": {
+			query: `1 1.5e-3 .5 0x1F r'\d' b"x" '''a
+b''' true`,
+			want: []tokenText{
+				{TokenNumber, "1"},
+				{TokenNumber, "1.5e-3"},
+				{TokenNumber, ".5"},
+				{TokenNumber, "0x1F"},
+				{TokenString, `r'\d'`},
+				{TokenString, `b"x"`},
+				{TokenString, "'''a\nb'''"},
+				{TokenKeyword, "true"},
+			},
+		},
+		"comments": {
+			query: "a -- b\n# c\n/* d\ne */ f",
+			want: []tokenText{
+				{TokenIdentifier, "a"},
+				{TokenComment, "-- b"},
+				{TokenComment, "# c"},
+				{TokenComment, "/* d\ne */"},
+				{TokenIdentifier, "f"},
+			},
+		},
+		"parameters and operators": {
+			query: "x>=@min AND @@project_id<>'p'||'q'",
+			want: []tokenText{
+				{TokenIdentifier, "x"},
+				{TokenOther, ">="},
+				{TokenParameter, "@min"},
+				{TokenKeyword, "AND"},
+				{TokenParameter, "@@project_id"},
+				{TokenOther, "<>"},
+				{TokenString, "'p'"},
+				{TokenOther, "||"},
+				{TokenString, "'q'"},
+			},
+		},
+		"unterminated comment": {
+			query: "select /* a",
+			want: []tokenText{
+				{TokenKeyword, "select"},
+				{TokenComment, "/* a"},
+			},
+		},
+		"multibyte identifiers": {
+			query: "select 列",
+			want: []tokenText{
+				{TokenKeyword, "select"},
+				{TokenIdentifier, "列"},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tokens := Tokenize(tc.query)
+
+			got := []tokenText{}
+			end := 0
+
+			for _, tok := range tokens {
+				if tok.Start != end {
+					t.Errorf("want token at %d, got at %d", end, tok.Start)
+				}
+				end = tok.End
+
+				if tok.Kind != TokenSpace {
+					got = append(got, tokenText{tok.Kind, tok.Text(tc.query)})
+				}
+			}
+
+			if end != len(tc.query) {
+				t.Errorf("want tokens to cover %d bytes, got: %d", len(tc.query), end)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package history
+
+import (
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+// mapRowsResult is bigquery.Result written before rows became positional. Rows
+// are maps keyed by column names, so duplicate columns were already collapsed.
+type mapRowsResult struct {
+	Query               string
+	Keys                []string
+	Schema              []*bigquery.Field
+	Rows                []map[string]bigqueryapi.Value
+	TotalRows           int64
+	TotalBytesProcessed int64
+	DryRun              bool
+	Cancelled           bool
+	EndTime             time.Time
+}
+
+// result converts rows into positional ones in the order of Keys.
+func (r *mapRowsResult) result() *bigquery.Result {
+	var rows [][]bigqueryapi.Value
+
+	if r.Rows != nil {
+		rows = make([][]bigqueryapi.Value, 0, len(r.Rows))
+
+		for _, m := range r.Rows {
+			row := make([]bigqueryapi.Value, 0, len(r.Keys))
+			for _, k := range r.Keys {
+				row = append(row, m[k])
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return &bigquery.Result{
+		Query:               r.Query,
+		Keys:                r.Keys,
+		Schema:              r.Schema,
+		Rows:                rows,
+		TotalRows:           r.TotalRows,
+		TotalBytesProcessed: r.TotalBytesProcessed,
+		DryRun:              r.DryRun,
+		Cancelled:           r.Cancelled,
+		EndTime:             r.EndTime,
+	}
+}
@@ -69,35 +69,148 @@ func (s *LocalStorage) Append(result *bigquery.Result) error {
 	return nil
 }
 
-func (s *LocalStorage) List() ([]*bigquery.Result, error) {
-	results := []*bigquery.Result{}
+func (s *LocalStorage) List() ([]*Entry, error) {
+	entries := []*Entry{}
 
 	err := s.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket(s.bucket)
 		c := b.Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var r bigquery.Result
+			uv, err := decompressZstd(v)
+			if err != nil {
+				return fmt.Errorf("decompress history from zstd: %w", err)
+			}
+
+			var e Entry
+
+			if err := gob.NewDecoder(bytes.NewReader(uv)).Decode(&e); err != nil {
+				// same as LastParameters
+				r, rerr := decodeResult(uv)
+				if rerr != nil {
+					return fmt.Errorf("decode result from gob: %w", rerr)
+				}
+
+				e = Entry{
+					Query:               r.Query,
+					Parameters:          r.Parameters,
+					TotalBytesProcessed: r.TotalBytesProcessed,
+					DryRun:              r.DryRun,
+					Cancelled:           r.Cancelled,
+					EndTime:             r.EndTime,
+				}
+			}
+
+			e.Key = string(k)
+
+			entries = append(entries, &e)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return []*Entry{}, fmt.Errorf("view: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *LocalStorage) Load(key string) (*bigquery.Result, error) {
+	var r *bigquery.Result
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(s.bucket).Get([]byte(key))
+		if v == nil {
+			return fmt.Errorf("entry %s not found", key)
+		}
+
+		uv, err := decompressZstd(v)
+		if err != nil {
+			return fmt.Errorf("decompress history from zstd: %w", err)
+		}
+
+		r, err = decodeResult(uv)
+		if err != nil {
+			return fmt.Errorf("decode result from gob: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view: %w", err)
+	}
+
+	return r, nil
+}
+
+// queryParameters is the part of a history entry read by LastParameters. gob
+// skips the other fields of bigquery.Result without building their values.
+type queryParameters struct {
+	Query      string
+	Parameters []bigquery.Parameter
+}
+
+func (s *LocalStorage) LastParameters(query string) ([]bigquery.Parameter, error) {
+	var params []bigquery.Parameter
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		c := b.Cursor()
 
+		// newest first
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
 			uv, err := decompressZstd(v)
 			if err != nil {
 				return fmt.Errorf("decompress history from zstd: %w", err)
 			}
 
-			if err := gob.NewDecoder(bytes.NewBuffer(uv)).Decode(&r); err != nil {
-				return fmt.Errorf("decode result from gob: %w", err)
+			var qp queryParameters
+
+			if err := gob.NewDecoder(bytes.NewReader(uv)).Decode(&qp); err != nil {
+				// gob fails to skip some nested values in entries of the old
+				// format, so such entries are decoded entirely
+				r, rerr := decodeResult(uv)
+				if rerr != nil {
+					return fmt.Errorf("decode result from gob: %w", rerr)
+				}
+
+				qp = queryParameters{Query: r.Query, Parameters: r.Parameters}
 			}
 
-			results = append(results, &r)
+			if qp.Query == query && len(qp.Parameters) > 0 {
+				params = qp.Parameters
+
+				return nil
+			}
 		}
 
 		return nil
 	})
 	if err != nil {
-		return []*bigquery.Result{}, fmt.Errorf("view: %w", err)
+		return nil, fmt.Errorf("view: %w", err)
+	}
+
+	return params, nil
+}
+
+// decodeResult decodes a history entry. Entries written before rows became
+// positional are converted from rows of maps.
+func decodeResult(b []byte) (*bigquery.Result, error) {
+	var r bigquery.Result
+
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&r)
+	if err == nil {
+		return &r, nil
+	}
+
+	var mr mapRowsResult
+
+	if merr := gob.NewDecoder(bytes.NewReader(b)).Decode(&mr); merr != nil {
+		// report the error for the current format
+		return nil, err
 	}
 
-	return results, nil
+	return mr.result(), nil
 }
 
 // Use nanoseconds as key
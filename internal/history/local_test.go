@@ -3,12 +3,15 @@ package history
 import (
 	"bytes"
 	"encoding/gob"
+	"math/big"
 	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
 	"github.com/google/go-cmp/cmp"
 	bolt "go.etcd.io/bbolt"
 
@@ -50,7 +53,19 @@ func TestLocalStorageAppendAndList(t *testing.T) {
 
 	results := []*bigquery.Result{
 		{
-			Keys:                []string{"foo", "bar"},
+			Keys: []string{"foo", "bar"},
+			Schema: []*bigquery.Field{
+				{Name: "foo", Type: "INTEGER", Mode: bigquery.ModeNullable},
+				{
+					Name:        "bar",
+					Type:        "RECORD",
+					Mode:        bigquery.ModeRepeated,
+					Description: "nested",
+					Fields: []*bigquery.Field{
+						{Name: "baz", Type: "STRING", Mode: bigquery.ModeRequired},
+					},
+				},
+			},
 			TotalBytesProcessed: 12345,
 			EndTime:             time.Date(2023, 5, 24, 12, 34, 56, 0, time.UTC),
 		},
@@ -72,8 +87,155 @@ func TestLocalStorageAppendAndList(t *testing.T) {
 		t.Errorf("(List) want no error, got: %s", err)
 	}
 
-	if diff := cmp.Diff(results, got); diff != "" {
-		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	want := []*Entry{
+		{
+			Key:                 "1684931696000000001",
+			TotalBytesProcessed: 12345,
+			EndTime:             time.Date(2023, 5, 24, 12, 34, 56, 0, time.UTC),
+		},
+		{
+			Key:                 "1684931696000000002",
+			TotalBytesProcessed: 12345,
+			EndTime:             time.Date(2023, 5, 25, 13, 24, 59, 0, time.UTC),
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(List) data mismatch (-want +got):\n%s", diff)
+	}
+
+	for i, e := range got {
+		r, err := s.Load(e.Key)
+		if err != nil {
+			t.Fatalf("(Load) want no error, got: %s", err)
+		}
+
+		if diff := cmp.Diff(results[i], r); diff != "" {
+			t.Errorf("(Load) data mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	if _, err := s.Load("0"); err == nil {
+		t.Error("(Load) want error for unknown key, got nil")
+	}
+}
+
+func TestLocalStorageAppend_types(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+
+	want := &bigquery.Result{
+		Keys: []string{"n", "t", "d", "dt", "ts"},
+		Rows: [][]bigqueryapi.Value{
+			{
+				big.NewRat(12345, 100),
+				civil.Time{Hour: 12, Minute: 34, Second: 56},
+				civil.Date{Year: 2024, Month: 1, Day: 2},
+				civil.DateTime{Date: civil.Date{Year: 2024, Month: 1, Day: 2}, Time: civil.Time{Hour: 3}},
+				time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+		EndTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	if err := s.Append(want); err != nil {
+		t.Fatalf("(Append) want no error, got: %s", err)
+	}
+
+	es, err := s.List()
+	if err != nil || len(es) != 1 {
+		t.Fatalf("(List) want 1 entry, got: %d, %v", len(es), err)
+	}
+
+	got, err := s.Load(es[0].Key)
+	if err != nil {
+		t.Fatalf("(Load) want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b *big.Rat) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("(Load) data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalStorageLastParameters(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewLocalStorage(filepath.Join(t.TempDir(), "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+
+	count := 0
+
+	s.tsFunc = func() time.Time {
+		count += 1
+
+		return time.Date(2023, 5, 24, 12, 34, 56, count, time.UTC)
+	}
+
+	query := "select @n"
+
+	results := []*bigquery.Result{
+		{
+			Query:      query,
+			Parameters: []bigquery.Parameter{{Name: "n", Type: "INT64", Value: "1"}},
+		},
+		{
+			Query:      query,
+			Parameters: []bigquery.Parameter{{Name: "n", Type: "INT64", Value: "2"}},
+			Keys:       []string{"n"},
+			Rows:       [][]bigqueryapi.Value{{int64(2)}},
+		},
+		{
+			Query:      "select @m",
+			Parameters: []bigquery.Parameter{{Name: "m", Type: "STRING", Value: "3"}},
+		},
+	}
+
+	for _, r := range results {
+		if err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testcases := map[string]struct {
+		query string
+		want  []bigquery.Parameter
+	}{
+		"latest run": {
+			query: query,
+			want:  []bigquery.Parameter{{Name: "n", Type: "INT64", Value: "2"}},
+		},
+		"never run": {
+			query: "select @x",
+			want:  nil,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := s.LastParameters(tc.query)
+			if err != nil {
+				t.Fatalf("want no error, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
 	}
 }
 
@@ -147,13 +309,12 @@ func TestLocalStorageCompatibility(t *testing.T) {
 		c := b.Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var r bigquery.Result
-
-			if err := gob.NewDecoder(bytes.NewBuffer(v)).Decode(&r); err != nil {
+			r, err := decodeResult(v)
+			if err != nil {
 				return err
 			}
 
-			got = append(got, &r)
+			got = append(got, r)
 		}
 
 		return nil
@@ -166,3 +327,74 @@ func TestLocalStorageCompatibility(t *testing.T) {
 		t.Errorf("data mismatch (-want +got):\n%s", diff)
 	}
 }
+
+// TestLocalStorageCompatibility_mapRows checks whether history written before
+// rows became positional, i.e. rows as maps keyed by column names, can be read.
+func TestLocalStorageCompatibility_mapRows(t *testing.T) {
+	t.Parallel()
+
+	db, err := bolt.Open(filepath.Join("testdata", "map_rows.db"), 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	s := &LocalStorage{
+		db:     db,
+		bucket: []byte("history"),
+		tsFunc: time.Now,
+	}
+
+	want := []*bigquery.Result{
+		{
+			Query: "select id, name, tags from t",
+			Keys:  []string{"id", "name", "tags"},
+			Rows: [][]bigqueryapi.Value{
+				{
+					int64(1),
+					"foo",
+					[]bigqueryapi.Value{
+						map[string]bigqueryapi.Value{"k": "a"},
+					},
+				},
+				{
+					int64(2),
+					nil,
+					// gob decodes an empty slice as nil
+					[]bigqueryapi.Value(nil),
+				},
+			},
+			TotalBytesProcessed: 12345,
+			EndTime:             time.Date(2023, 5, 24, 12, 34, 56, 0, time.UTC),
+		},
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		t.Fatalf("(List) want no error, got: %s", err)
+	}
+
+	if len(entries) != 1 || entries[0].Query != want[0].Query || !entries[0].EndTime.Equal(want[0].EndTime) {
+		t.Fatalf("(List) want the entry listed, got: %#v", entries)
+	}
+
+	got, err := s.Load(entries[0].Key)
+	if err != nil {
+		t.Fatalf("(Load) want no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(want[0], got); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+	// entries of the old format are looked up as well
+	params, err := s.LastParameters("select id, name, tags from t")
+	if err != nil {
+		t.Fatalf("(LastParameters) want no error, got: %s", err)
+	}
+
+	if params != nil {
+		t.Errorf("want no parameters, got: %#v", params)
+	}
+}
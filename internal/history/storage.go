@@ -2,6 +2,7 @@ package history
 
 import (
 	"encoding/gob"
+	"math/big"
 	"time"
 
 	bigqueryapi "cloud.google.com/go/bigquery"
@@ -13,7 +14,29 @@ import (
 type Storage interface {
 	Close() error
 	Append(result *bigquery.Result) error
-	List() ([]*bigquery.Result, error)
+	// List returns all entries in chronological order. Rows and the other
+	// details of results are not decoded; Load reads them.
+	List() ([]*Entry, error)
+	// Load returns the whole result of the entry with the given key.
+	Load(key string) (*bigquery.Result, error)
+	// LastParameters returns the parameters given to the latest run of the
+	// query, or nil if it has never run with parameters. Like List, rows are
+	// not decoded.
+	LastParameters(query string) ([]bigquery.Parameter, error)
+}
+
+// Entry is the part of a history entry shown in the list of history. Fields
+// other than Key have the same names as those of bigquery.Result, so that gob
+// decodes only them.
+type Entry struct {
+	// Key identifies the entry in the storage
+	Key                 string
+	Query               string
+	Parameters          []bigquery.Parameter
+	TotalBytesProcessed int64
+	DryRun              bool
+	Cancelled           bool
+	EndTime             time.Time
 }
 
 func init() {
@@ -21,5 +44,8 @@ func init() {
 	gob.Register([]bigqueryapi.Value{})
 	gob.Register(time.Time{})
 	gob.Register(civil.Date{})
+	gob.Register(civil.Time{})
 	gob.Register(civil.DateTime{})
+	// NUMERIC and BIGNUMERIC
+	gob.Register((*big.Rat)(nil))
 }
@@ -0,0 +1,222 @@
+// Package profile loads named sets of settings applied to every BigQuery job.
+//
+// Profiles are written in an INI-like file, one section for each profile:
+//
+//	[default]
+//	project = my-project
+//	location = asia-northeast1
+//	dataset = my-project.analytics
+//	labels = team=data, env=dev
+//	priority = interactive
+//	legacy_sql = false
+//
+// Lines starting with "#" or ";" are comments.
+package profile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+// DefaultName is the profile used if none is specified.
+const DefaultName = "default"
+
+const (
+	PriorityInteractive = "interactive"
+	PriorityBatch       = "batch"
+)
+
+// Profile is a set of settings applied to BigQuery jobs. Empty fields leave the
+// defaults of the BigQuery API.
+type Profile struct {
+	Name string
+	// ProjectID is the project jobs run in, unless a project is given on the
+	// command line
+	ProjectID string
+	// Location is where jobs run, e.g. "asia-northeast1"
+	Location string
+	// DefaultProjectID and DefaultDatasetID qualify table names without a
+	// dataset. DefaultProjectID is empty if the project of jobs is used.
+	DefaultProjectID string
+	DefaultDatasetID string
+	// Labels are attached to jobs in addition to the automatic ones
+	Labels map[string]string
+	// Priority is PriorityInteractive or PriorityBatch
+	Priority  string
+	LegacySQL bool
+}
+
+// Load reads the profile of the given name from the file. A missing file or
+// section is not an error for DefaultName, which gives an empty profile.
+func Load(filename, name string) (*Profile, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) && name == DefaultName {
+		return &Profile{Name: name}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("open profiles: %w", err)
+	}
+	defer f.Close()
+
+	p, err := Parse(f, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return p, nil
+}
+
+// Parse reads the profile of the given name. Sections of other profiles are
+// skipped without validation.
+func Parse(r io.Reader, name string) (*Profile, error) {
+	var (
+		p       *Profile
+		current string
+		lineNum int
+	)
+
+	sc := bufio.NewScanner(r)
+
+	for sc.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(sc.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+
+			if current == name && p == nil {
+				p = &Profile{Name: name}
+			}
+
+			continue
+		}
+
+		if current != name {
+			continue
+		}
+
+		if p == nil {
+			return nil, fmt.Errorf("line %d: setting outside of a profile", lineNum)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want key = value, got %q", lineNum, line)
+		}
+
+		if err := p.set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read profiles: %w", err)
+	}
+
+	if p == nil {
+		if name == DefaultName {
+			return &Profile{Name: name}, nil
+		}
+
+		return nil, fmt.Errorf("profile %q is not found", name)
+	}
+
+	return p, nil
+}
+
+func (p *Profile) set(key, value string) error {
+	switch key {
+	case "project":
+		p.ProjectID = value
+
+	case "location":
+		p.Location = value
+
+	case "dataset":
+		// a domain-scoped project ID has dots before the colon, e.g.
+		// google.com:project, so the dataset is after the last dot
+		project, dataset := "", value
+		if i := strings.LastIndex(value, "."); i >= 0 {
+			project, dataset = value[:i], value[i+1:]
+
+			if project == "" || strings.Contains(project[strings.LastIndex(project, ":")+1:], ".") {
+				return fmt.Errorf("invalid dataset %q, want [project.]dataset", value)
+			}
+		}
+
+		if dataset == "" {
+			return fmt.Errorf("invalid dataset %q, want [project.]dataset", value)
+		}
+
+		p.DefaultProjectID = project
+		p.DefaultDatasetID = dataset
+
+	case "labels":
+		labels, err := parseLabels(value)
+		if err != nil {
+			return err
+		}
+
+		p.Labels = labels
+
+	case "priority":
+		if value != PriorityInteractive && value != PriorityBatch {
+			return fmt.Errorf("invalid priority %q, want %s or %s", value, PriorityInteractive, PriorityBatch)
+		}
+
+		p.Priority = value
+
+	case "legacy_sql":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid legacy_sql %q: %w", value, err)
+		}
+
+		p.LegacySQL = b
+
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+
+	return nil
+}
+
+// parseLabels parses comma-separated key=value pairs.
+func parseLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid label %q, want key=value", kv)
+		}
+
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+
+		if err := bigquery.ValidateLabel(k, v); err != nil {
+			return nil, err
+		}
+
+		labels[k] = v
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,173 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testProfiles = `# bqc profiles
+[default]
+project = my-project
+location = asia-northeast1
+dataset = analytics
+
+[prod]
+project = prod-project
+; fully-qualified dataset
+dataset = shared-project.warehouse
+labels = team=data, env=prod
+priority = batch
+legacy_sql = true
+
+[broken]
+priority = urgent
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		text    string
+		name    string
+		want    *Profile
+		wantErr string
+	}{
+		"default": {
+			text: testProfiles,
+			name: DefaultName,
+			want: &Profile{
+				Name:             DefaultName,
+				ProjectID:        "my-project",
+				Location:         "asia-northeast1",
+				DefaultDatasetID: "analytics",
+			},
+		},
+		"all settings": {
+			text: testProfiles,
+			name: "prod",
+			want: &Profile{
+				Name:             "prod",
+				ProjectID:        "prod-project",
+				DefaultProjectID: "shared-project",
+				DefaultDatasetID: "warehouse",
+				Labels:           map[string]string{"team": "data", "env": "prod"},
+				Priority:         PriorityBatch,
+				LegacySQL:        true,
+			},
+		},
+		"domain-scoped project": {
+			text: "[default]\ndataset = example.com:my-project.analytics\n",
+			name: DefaultName,
+			want: &Profile{
+				Name:             DefaultName,
+				DefaultProjectID: "example.com:my-project",
+				DefaultDatasetID: "analytics",
+			},
+		},
+		"invalid dataset": {
+			text:    "[default]\ndataset = a.b.c\n",
+			name:    DefaultName,
+			wantErr: `line 2: invalid dataset "a.b.c"`,
+		},
+		"empty project": {
+			text:    "[default]\ndataset = .analytics\n",
+			name:    DefaultName,
+			wantErr: `line 2: invalid dataset ".analytics"`,
+		},
+		"no default section": {
+			text: "[prod]\nproject = prod-project\n",
+			name: DefaultName,
+			want: &Profile{Name: DefaultName},
+		},
+		"not found": {
+			text:    testProfiles,
+			name:    "staging",
+			wantErr: `profile "staging" is not found`,
+		},
+		"invalid value": {
+			text:    testProfiles,
+			name:    "broken",
+			wantErr: `line 16: invalid priority "urgent"`,
+		},
+		"unknown setting": {
+			text:    "[default]\nregion = us\n",
+			name:    DefaultName,
+			wantErr: `line 2: unknown setting "region"`,
+		},
+		"invalid label": {
+			text:    "[default]\nlabels = team\n",
+			name:    DefaultName,
+			wantErr: `line 2: invalid label "team"`,
+		},
+		"invalid label key": {
+			text:    "[default]\nlabels = Team=data\n",
+			name:    DefaultName,
+			wantErr: `line 2: invalid label key "Team"`,
+		},
+		"invalid label value": {
+			text:    "[default]\nlabels = team=Data Platform\n",
+			name:    DefaultName,
+			wantErr: `line 2: invalid value "Data Platform" of label team`,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(strings.NewReader(tc.text), tc.name)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("want error containing %q, got: %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "profiles")
+
+	p, err := Load(filename, DefaultName)
+	if err != nil {
+		t.Fatalf("want no error for the default profile, got: %s", err)
+	}
+
+	if diff := cmp.Diff(&Profile{Name: DefaultName}, p); diff != "" {
+		t.Errorf("(-want +got)\n%s", diff)
+	}
+
+	if _, err := Load(filename, "prod"); err == nil {
+		t.Error("want error for a named profile")
+	}
+
+	if err := os.WriteFile(filename, []byte(testProfiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = Load(filename, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.ProjectID != "prod-project" {
+		t.Errorf("want project: prod-project, got: %q", p.ProjectID)
+	}
+}
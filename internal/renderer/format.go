@@ -0,0 +1,155 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	timestampFormat = "2006-01-02 15:04:05.999999 MST"
+)
+
+// valueFormat decides how NULL and TIMESTAMP values are written.
+type valueFormat struct {
+	// null is the text of NULL
+	null string
+	// utcTimestamp formats TIMESTAMP values in UTC with microseconds. They are
+	// formatted by time.Time.String otherwise.
+	utcTimestamp bool
+}
+
+var (
+	// displayFormat is used in the result pane
+	displayFormat = valueFormat{null: "NULL", utcTimestamp: true}
+	// batchFormat keeps NULL and TIMESTAMP values in batch mode as they were
+	// before values were formatted by their types. NUMERIC is written as a
+	// decimal and BYTES in base64, like in the result pane.
+	batchFormat = valueFormat{null: "<nil>"}
+	// csvFormat writes NULL as an empty field
+	csvFormat = valueFormat{null: ""}
+)
+
+// FormatValue formats a BigQuery value as text according to the field type,
+// as shown in the result pane. field may be nil if the schema is unknown.
+func FormatValue(field *bigquery.Field, v bigqueryapi.Value) string {
+	return displayFormat.format(field, v)
+}
+
+func (f valueFormat) format(field *bigquery.Field, v bigqueryapi.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return f.null
+	case *big.Rat:
+		return ratString(v)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		if f.utcTimestamp && field != nil && field.Type == string(bigqueryapi.TimestampFieldType) {
+			return v.UTC().Format(timestampFormat)
+		}
+
+		return fmt.Sprintf("%v", v)
+	case []bigqueryapi.Value:
+		if isRecord(field) {
+			return f.formatRecord(field.Fields, v)
+		}
+
+		elem := elemField(field)
+
+		vs := make([]string, 0, len(v))
+		for _, e := range v {
+			vs = append(vs, f.format(elem, e))
+		}
+
+		return "[" + strings.Join(vs, ", ") + "]"
+	case map[string]bigqueryapi.Value:
+		// RECORD in history recorded before rows became positional
+		return f.formatRecordMap(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatRecord formats a RECORD value as {name: value, ...}.
+func (f valueFormat) formatRecord(fields []*bigquery.Field, values []bigqueryapi.Value) string {
+	vs := make([]string, 0, len(fields))
+	for i, field := range fields {
+		vs = append(vs, fmt.Sprintf("%s: %s", field.Name, f.format(field, valueAt(values, i))))
+	}
+
+	return "{" + strings.Join(vs, ", ") + "}"
+}
+
+// formatRecordMap formats a RECORD value without schema, ordered by field name.
+func (f valueFormat) formatRecordMap(v map[string]bigqueryapi.Value) string {
+	names := make([]string, 0, len(v))
+	for k := range v {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	vs := make([]string, 0, len(names))
+	for _, n := range names {
+		vs = append(vs, fmt.Sprintf("%s: %s", n, f.format(nil, v[n])))
+	}
+
+	return "{" + strings.Join(vs, ", ") + "}"
+}
+
+// isRecord reports whether values of the field are single RECORDs, which are
+// loaded as values of their nested fields.
+func isRecord(field *bigquery.Field) bool {
+	return field != nil &&
+		field.Type == string(bigqueryapi.RecordFieldType) &&
+		!field.IsRepeated() &&
+		len(field.Fields) > 0
+}
+
+// elemField returns the schema of elements of a REPEATED field.
+func elemField(field *bigquery.Field) *bigquery.Field {
+	if field == nil {
+		return nil
+	}
+
+	elem := *field
+	elem.Mode = bigquery.ModeNullable
+
+	return &elem
+}
+
+// valueAt returns the i-th value, or nil if values are fewer.
+func valueAt(values []bigqueryapi.Value, i int) bigqueryapi.Value {
+	if i < 0 || i >= len(values) {
+		return nil
+	}
+
+	return values[i]
+}
+
+// fieldAt returns the schema of the i-th column, or nil if it is unknown.
+func fieldAt(result *bigquery.Result, i int) *bigquery.Field {
+	if i < 0 || i >= len(result.Schema) {
+		return nil
+	}
+
+	return result.Schema[i]
+}
+
+// formatRow formats values of a row in the order of the columns.
+func (f valueFormat) formatRow(result *bigquery.Result, row []bigqueryapi.Value) []string {
+	vs := make([]string, 0, len(result.Keys))
+	for i := range result.Keys {
+		vs = append(vs, f.format(fieldAt(result, i), valueAt(row, i)))
+	}
+
+	return vs
+}
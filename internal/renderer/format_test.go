@@ -0,0 +1,86 @@
+package renderer
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+func TestFormatValue(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		field *bigquery.Field
+		value bigqueryapi.Value
+		want  string
+	}{
+		"null": {
+			value: nil,
+			want:  "NULL",
+		},
+		"numeric": {
+			field: &bigquery.Field{Type: "NUMERIC"},
+			value: big.NewRat(1, 4),
+			want:  "0.25",
+		},
+		"bytes": {
+			field: &bigquery.Field{Type: "BYTES"},
+			value: []byte("foo"),
+			want:  "Zm9v",
+		},
+		"timestamp": {
+			field: &bigquery.Field{Type: "TIMESTAMP"},
+			value: time.Date(2023, 5, 3, 12, 34, 56, 789000000, time.FixedZone("JST", 9*60*60)),
+			want:  "2023-05-03 03:34:56.789 UTC",
+		},
+		"repeated": {
+			field: &bigquery.Field{Type: "INTEGER", Mode: bigquery.ModeRepeated},
+			value: []bigqueryapi.Value{int64(1), nil, int64(3)},
+			want:  "[1, NULL, 3]",
+		},
+		"record in schema order": {
+			field: &bigquery.Field{
+				Type: "RECORD",
+				Fields: []*bigquery.Field{
+					{Name: "z", Type: "STRING"},
+					{Name: "a", Type: "INTEGER"},
+				},
+			},
+			value: []bigqueryapi.Value{"foo", int64(1)},
+			want:  "{z: foo, a: 1}",
+		},
+		"repeated record": {
+			field: &bigquery.Field{
+				Type: "RECORD",
+				Mode: bigquery.ModeRepeated,
+				Fields: []*bigquery.Field{
+					{Name: "k", Type: "STRING"},
+				},
+			},
+			value: []bigqueryapi.Value{
+				[]bigqueryapi.Value{"a"},
+				[]bigqueryapi.Value{"b"},
+			},
+			want: "[{k: a}, {k: b}]",
+		},
+		"record recorded as map": {
+			value: map[string]bigqueryapi.Value{"z": "foo", "a": int64(1)},
+			want:  "{a: 1, z: foo}",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := FormatValue(tc.field, tc.value); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+type JSONRenderer struct{}
+
+var _ Renderer = (*JSONRenderer)(nil)
+
+// Render renders the result as a JSON array of objects.
+func (r *JSONRenderer) Render(result *bigquery.Result) (string, error) {
+	var b bytes.Buffer
+
+	keys := uniqueKeys(result.Keys)
+
+	b.WriteString("[")
+
+	for i, row := range result.Rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		if err := writeJSONRow(&b, result, keys, row); err != nil {
+			return "", fmt.Errorf("write row %d to JSON: %w", i, err)
+		}
+	}
+
+	b.WriteString("]")
+
+	var out bytes.Buffer
+
+	if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil {
+		return "", fmt.Errorf("indent JSON: %w", err)
+	}
+
+	out.WriteString("\n")
+
+	return out.String(), nil
+}
+
+type JSONLinesRenderer struct{}
+
+var _ Renderer = (*JSONLinesRenderer)(nil)
+
+// Render renders the result as newline-delimited JSON, one object per row.
+func (r *JSONLinesRenderer) Render(result *bigquery.Result) (string, error) {
+	var b bytes.Buffer
+
+	keys := uniqueKeys(result.Keys)
+
+	for i, row := range result.Rows {
+		if err := writeJSONRow(&b, result, keys, row); err != nil {
+			return "", fmt.Errorf("write row %d to JSON: %w", i, err)
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// writeJSONRow writes row as a JSON object whose keys are in the order of the
+// columns. keys are the column names made unique by uniqueKeys.
+func writeJSONRow(b *bytes.Buffer, result *bigquery.Result, keys []string, row []bigqueryapi.Value) error {
+	o := jsonObject{
+		keys:   keys,
+		values: make([]any, 0, len(keys)),
+	}
+
+	for i := range keys {
+		o.values = append(o.values, jsonValue(fieldAt(result, i), valueAt(row, i)))
+	}
+
+	j, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	b.Write(j)
+
+	return nil
+}
+
+// uniqueKeys returns keys with duplicates renamed to name_1, name_2, ... so that
+// no value is hidden by a later one with the same key in JSON. Suffixes already
+// used by other keys are skipped.
+func uniqueKeys(keys []string) []string {
+	used := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		used[k] = true
+	}
+
+	seen := make(map[string]bool, len(keys))
+	uniq := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			uniq = append(uniq, k)
+
+			continue
+		}
+
+		n := 1
+		for used[fmt.Sprintf("%s_%d", k, n)] {
+			n++
+		}
+
+		u := fmt.Sprintf("%s_%d", k, n)
+		used[u] = true
+		uniq = append(uniq, u)
+	}
+
+	return uniq
+}
+
+// jsonObject is a JSON object which keeps the order of its keys. Maps cannot be
+// used since encoding/json sorts their keys.
+type jsonObject struct {
+	keys   []string
+	values []any
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteString("{")
+
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		kj, err := json.Marshal(k)
+		if err != nil {
+			return nil, fmt.Errorf("marshal key %q: %w", k, err)
+		}
+
+		vj, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, fmt.Errorf("marshal value of %q: %w", k, err)
+		}
+
+		b.Write(kj)
+		b.WriteString(":")
+		b.Write(vj)
+	}
+
+	b.WriteString("}")
+
+	return b.Bytes(), nil
+}
+
+// jsonValue converts a BigQuery value into a value that encoding/json marshals
+// in the same way as BigQuery's own JSON export. field may be nil if the schema
+// is unknown.
+func jsonValue(field *bigquery.Field, v bigqueryapi.Value) any {
+	switch v := v.(type) {
+	case *big.Rat:
+		// NUMERIC and BIGNUMERIC are kept as strings to avoid losing precision
+		return ratString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+
+		return v
+	case map[string]bigqueryapi.Value:
+		// RECORD in history recorded before rows became positional
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = jsonValue(nil, e)
+		}
+
+		return m
+	case []bigqueryapi.Value:
+		if isRecord(field) {
+			o := jsonObject{
+				keys:   make([]string, 0, len(field.Fields)),
+				values: make([]any, 0, len(field.Fields)),
+			}
+
+			for i, f := range field.Fields {
+				o.keys = append(o.keys, f.Name)
+				o.values = append(o.values, jsonValue(f, valueAt(v, i)))
+			}
+
+			o.keys = uniqueKeys(o.keys)
+
+			return o
+		}
+
+		elem := elemField(field)
+
+		vs := make([]any, len(v))
+		for i, e := range v {
+			vs[i] = jsonValue(elem, e)
+		}
+
+		return vs
+	case fmt.Stringer:
+		// civil.Date, civil.Time, civil.DateTime, etc.
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// ratString formats r as a decimal without trailing zeros.
+func ratString(r *big.Rat) string {
+	s := bigqueryapi.BigNumericString(r)
+
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+
+	return s
+}
@@ -0,0 +1,291 @@
+package renderer
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+func TestJSONRender(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		result *bigquery.Result
+		want   string
+	}{
+		"success": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"foo",
+					"bar",
+					"baz",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+					},
+					{
+						nil,
+						2,
+						time.Date(2023, 5, 4, 12, 34, 56, 0, time.UTC),
+					},
+				},
+			},
+			want: `[
+  {
+    "foo": "foovalue",
+    "bar": 1,
+    "baz": "2023-05-03T12:34:56Z"
+  },
+  {
+    "foo": null,
+    "bar": 2,
+    "baz": "2023-05-04T12:34:56Z"
+  }
+]
+`,
+		},
+		"no rows": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"foo",
+				},
+				Rows: [][]bigqueryapi.Value{},
+			},
+			want: `[]
+`,
+		},
+		"types": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"numeric",
+					"float",
+					"date",
+					"struct",
+					"array",
+				},
+				Schema: []*bigquery.Field{
+					{Name: "numeric", Type: "NUMERIC", Mode: bigquery.ModeNullable},
+					{Name: "float", Type: "FLOAT", Mode: bigquery.ModeNullable},
+					{Name: "date", Type: "DATE", Mode: bigquery.ModeNullable},
+					{
+						Name: "struct",
+						Type: "RECORD",
+						Mode: bigquery.ModeNullable,
+						Fields: []*bigquery.Field{
+							{Name: "b", Type: "STRING", Mode: bigquery.ModeNullable},
+							{Name: "a", Type: "INTEGER", Mode: bigquery.ModeNullable},
+						},
+					},
+					{Name: "array", Type: "INTEGER", Mode: bigquery.ModeRepeated},
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						big.NewRat(3, 2),
+						math.Inf(1),
+						civil.Date{Year: 2023, Month: 5, Day: 3},
+						[]bigqueryapi.Value{
+							"x",
+							1,
+						},
+						[]bigqueryapi.Value{
+							int64(1),
+							int64(2),
+						},
+					},
+				},
+			},
+			want: `[
+  {
+    "numeric": "1.5",
+    "float": "Infinity",
+    "date": "2023-05-03",
+    "struct": {
+      "b": "x",
+      "a": 1
+    },
+    "array": [
+      1,
+      2
+    ]
+  }
+]
+`,
+		},
+		"duplicate column and field names": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"a",
+					"a",
+				},
+				Schema: []*bigquery.Field{
+					{Name: "a", Type: "INTEGER", Mode: bigquery.ModeNullable},
+					{
+						Name: "a",
+						Type: "RECORD",
+						Mode: bigquery.ModeNullable,
+						Fields: []*bigquery.Field{
+							{Name: "", Type: "INTEGER", Mode: bigquery.ModeNullable},
+							{Name: "", Type: "INTEGER", Mode: bigquery.ModeNullable},
+						},
+					},
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						int64(1),
+						[]bigqueryapi.Value{
+							int64(2),
+							int64(3),
+						},
+					},
+				},
+			},
+			want: `[
+  {
+    "a": 1,
+    "a_1": {
+      "": 2,
+      "_1": 3
+    }
+  }
+]
+`,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rdr := &JSONRenderer{}
+
+			got, err := rdr.Render(tc.result)
+			if err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got, cmpopts.AcyclicTransformer("SplitLines", func(s string) []string {
+				return strings.Split(s, "\n")
+			})); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJSONLinesRender(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		result *bigquery.Result
+		want   string
+	}{
+		"success": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"foo",
+					"bar",
+					"baz",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+					},
+					{
+						"foovalue2",
+						2,
+						time.Date(2023, 5, 4, 12, 34, 56, 0, time.UTC),
+					},
+				},
+			},
+			want: `{"foo":"foovalue","bar":1,"baz":"2023-05-03T12:34:56Z"}
+{"foo":"foovalue2","bar":2,"baz":"2023-05-04T12:34:56Z"}
+`,
+		},
+		"duplicate column names": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"id",
+					"id",
+					"id_1",
+					"id",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						int64(1),
+						int64(2),
+						int64(3),
+						int64(4),
+					},
+				},
+			},
+			want: `{"id":1,"id_2":2,"id_1":3,"id_3":4}
+`,
+		},
+		"nested record in history recorded as map": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"struct",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						map[string]bigqueryapi.Value{
+							"b": "x",
+							"a": 1,
+						},
+					},
+				},
+			},
+			want: `{"struct":{"a":1,"b":"x"}}
+`,
+		},
+		"numeric is kept as string": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"numeric",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						big.NewRat(12345678901, 100),
+					},
+				},
+			},
+			want: `{"numeric":"123456789.01"}
+`,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rdr := &JSONLinesRenderer{}
+
+			got, err := rdr.Render(tc.result)
+			if err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got, cmpopts.AcyclicTransformer("SplitLines", func(s string) []string {
+				return strings.Split(s, "\n")
+			})); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -59,6 +59,9 @@ func (r *TableRenderer) Render(result *bigquery.Result) (string, error) {
 				Formatting: tw.CellFormatting{
 					Alignment: tw.AlignRight,
 				},
+				Alignment: tw.CellAlignment{
+					PerColumn: columnAlignments(result),
+				},
 			},
 		}),
 	)
@@ -69,12 +72,7 @@ func (r *TableRenderer) Render(result *bigquery.Result) (string, error) {
 	table.Header(result.Keys)
 
 	for _, r := range result.Rows {
-		vs := []string{}
-		for _, k := range result.Keys {
-			vs = append(vs, fmt.Sprintf("%v", r[k]))
-		}
-
-		table.Append(vs)
+		table.Append(batchFormat.formatRow(result, r))
 	}
 
 	table.Render()
@@ -82,6 +80,26 @@ func (r *TableRenderer) Render(result *bigquery.Result) (string, error) {
 	return b.String(), nil
 }
 
+// columnAlignments aligns numbers to the right and others to the left. It
+// returns nil if the schema is unknown, so that all columns are aligned to the
+// right.
+func columnAlignments(result *bigquery.Result) []tw.Align {
+	if len(result.Schema) == 0 {
+		return nil
+	}
+
+	as := make([]tw.Align, 0, len(result.Keys))
+	for i := range result.Keys {
+		if f := fieldAt(result, i); f == nil || f.IsNumeric() {
+			as = append(as, tw.AlignRight)
+		} else {
+			as = append(as, tw.AlignLeft)
+		}
+	}
+
+	return as
+}
+
 type MarkdownRenderer struct{}
 
 var _ Renderer = (*MarkdownRenderer)(nil)
@@ -132,6 +150,9 @@ func (r *MarkdownRenderer) Render(result *bigquery.Result) (string, error) {
 				Formatting: tw.CellFormatting{
 					Alignment: tw.AlignRight,
 				},
+				Alignment: tw.CellAlignment{
+					PerColumn: columnAlignments(result),
+				},
 			},
 		}),
 	)
@@ -142,12 +163,7 @@ func (r *MarkdownRenderer) Render(result *bigquery.Result) (string, error) {
 	table.Header(result.Keys)
 
 	for _, r := range result.Rows {
-		vs := []string{}
-		for _, k := range result.Keys {
-			vs = append(vs, fmt.Sprintf("%v", r[k]))
-		}
-
-		table.Append(vs)
+		table.Append(batchFormat.formatRow(result, r))
 	}
 
 	table.Render()
@@ -160,23 +176,30 @@ type TSVRenderer struct{}
 var _ Renderer = (*TSVRenderer)(nil)
 
 func (r *TSVRenderer) Render(result *bigquery.Result) (string, error) {
+	return renderDelimited(result, '\t', "TSV", batchFormat)
+}
+
+type CSVRenderer struct{}
+
+var _ Renderer = (*CSVRenderer)(nil)
+
+func (r *CSVRenderer) Render(result *bigquery.Result) (string, error) {
+	return renderDelimited(result, ',', "CSV", csvFormat)
+}
+
+func renderDelimited(result *bigquery.Result, comma rune, format string, vf valueFormat) (string, error) {
 	var b bytes.Buffer
 
 	table := csv.NewWriter(&b)
-	table.Comma = '\t'
+	table.Comma = comma
 
 	if err := table.Write(result.Keys); err != nil {
-		return "", fmt.Errorf("write header to TSV: %w", err)
+		return "", fmt.Errorf("write header to %s: %w", format, err)
 	}
 
 	for _, r := range result.Rows {
-		vs := []string{}
-		for _, k := range result.Keys {
-			vs = append(vs, fmt.Sprintf("%v", r[k]))
-		}
-
-		if err := table.Write(vs); err != nil {
-			return "", fmt.Errorf("write row to TSV: %w", err)
+		if err := table.Write(vf.formatRow(result, r)); err != nil {
+			return "", fmt.Errorf("write row to %s: %w", format, err)
 		}
 	}
 
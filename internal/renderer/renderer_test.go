@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"math/big"
 	"strings"
 	"testing"
 	"time"
@@ -26,11 +27,11 @@ func TestTableRender(t *testing.T) {
 					"bar",
 					"baz",
 				},
-				Rows: []map[string]bigqueryapi.Value{
+				Rows: [][]bigqueryapi.Value{
 					{
-						"foo": "foovalue",
-						"bar": 1,
-						"baz": time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
 					},
 				},
 			},
@@ -50,13 +51,13 @@ func TestTableRender(t *testing.T) {
 					"qux",
 					"quux",
 				},
-				Rows: []map[string]bigqueryapi.Value{
+				Rows: [][]bigqueryapi.Value{
 					{
-						"foo":  "foovalue",
-						"bar":  1,
-						"baz":  time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
-						"qux":  "qux",
-						"quux": "quuuuuu uuuuuuuuuuuuuu uuuuuuuuux",
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+						"qux",
+						"quuuuuu uuuuuuuuuuuuuu uuuuuuuuux",
 					},
 				},
 			},
@@ -65,6 +66,40 @@ func TestTableRender(t *testing.T) {
 +----------+-----+-------------------------------+-----+-----------------------------------+
 | foovalue |   1 | 2023-05-03 12:34:56 +0000 UTC | qux | quuuuuu uuuuuuuuuuuuuu uuuuuuuuux |
 +----------+-----+-------------------------------+-----+-----------------------------------+
+`,
+		},
+		"aligned and formatted by type": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"name",
+					"score",
+					"ts",
+				},
+				Schema: []*bigquery.Field{
+					{Name: "name", Type: "STRING", Mode: bigquery.ModeNullable},
+					{Name: "score", Type: "NUMERIC", Mode: bigquery.ModeNullable},
+					{Name: "ts", Type: "TIMESTAMP", Mode: bigquery.ModeNullable},
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						"foo",
+						big.NewRat(3, 2),
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+					},
+					{
+						"barbaz",
+						big.NewRat(12, 1),
+						nil,
+					},
+				},
+			},
+			want: `+--------+-------+-------------------------------+
+|  name  | score |              ts               |
++--------+-------+-------------------------------+
+| foo    |   1.5 | 2023-05-03 12:34:56 +0000 UTC |
++--------+-------+-------------------------------+
+| barbaz |    12 | <nil>                         |
++--------+-------+-------------------------------+
 `,
 		},
 	}
@@ -104,11 +139,11 @@ func TestMarkdownRender(t *testing.T) {
 					"bar",
 					"baz",
 				},
-				Rows: []map[string]bigqueryapi.Value{
+				Rows: [][]bigqueryapi.Value{
 					{
-						"foo": "foovalue",
-						"bar": 1,
-						"baz": time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
 					},
 				},
 			},
@@ -126,13 +161,13 @@ func TestMarkdownRender(t *testing.T) {
 					"qux",
 					"quux",
 				},
-				Rows: []map[string]bigqueryapi.Value{
+				Rows: [][]bigqueryapi.Value{
 					{
-						"foo":  "foovalue",
-						"bar":  1,
-						"baz":  time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
-						"qux":  "qux",
-						"quux": "quuuuuu uuuuuuuuuuuuuu uuuuuuuuux",
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+						"qux",
+						"quuuuuu uuuuuuuuuuuuuu uuuuuuuuux",
 					},
 				},
 			},
@@ -178,11 +213,11 @@ func TestTSVRender(t *testing.T) {
 					"bar",
 					"baz",
 				},
-				Rows: []map[string]bigqueryapi.Value{
+				Rows: [][]bigqueryapi.Value{
 					{
-						"foo": "foovalue",
-						"bar": 1,
-						"baz": time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
 					},
 				},
 			},
@@ -199,13 +234,13 @@ foovalue	1	2023-05-03 12:34:56 +0000 UTC
 					"qux",
 					"quux",
 				},
-				Rows: []map[string]bigqueryapi.Value{
+				Rows: [][]bigqueryapi.Value{
 					{
-						"foo":  "foovalue",
-						"bar":  1,
-						"baz":  time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
-						"qux":  "qux",
-						"quux": "quuuuuu uuuuuuuuuuuuuu uuuuuuuuux",
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+						"qux",
+						"quuuuuu uuuuuuuuuuuuuu uuuuuuuuux",
 					},
 				},
 			},
@@ -235,3 +270,103 @@ foovalue	1	2023-05-03 12:34:56 +0000 UTC	qux	quuuuuu uuuuuuuuuuuuuu uuuuuuuuux
 		})
 	}
 }
+
+func TestCSVRender(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		result *bigquery.Result
+		want   string
+	}{
+		"success": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"foo",
+					"bar",
+					"baz",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						"foovalue",
+						1,
+						time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+					},
+				},
+			},
+			want: `foo,bar,baz
+foovalue,1,2023-05-03 12:34:56 +0000 UTC
+`,
+		},
+		"duplicate column names": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"id",
+					"id",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						int64(1),
+						int64(2),
+					},
+				},
+			},
+			want: `id,id
+1,2
+`,
+		},
+		"null is an empty field": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"foo",
+					"bar",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						nil,
+						"barvalue",
+					},
+				},
+			},
+			want: `foo,bar
+,barvalue
+`,
+		},
+		"values containing comma are quoted": {
+			result: &bigquery.Result{
+				Keys: []string{
+					"foo",
+					"bar",
+				},
+				Rows: [][]bigqueryapi.Value{
+					{
+						"foo,value",
+						`say "hi"`,
+					},
+				},
+			},
+			want: `foo,bar
+"foo,value","say ""hi"""
+`,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rdr := &CSVRenderer{}
+
+			got, err := rdr.Render(tc.result)
+			if err != nil {
+				t.Errorf("want no error, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got, cmpopts.AcyclicTransformer("SplitLines", func(s string) []string {
+				return strings.Split(s, "\n")
+			})); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/checkpoint"
+)
+
+const (
+	bufferNamePrefix = "query-"
+
+	modalButtonRename = "Rename"
+	modalButtonClose  = "Close"
+)
+
+// buffer is a query edited in a tab of Query page. Each buffer keeps its own
+// result, and is saved to its own checkpoint. Fields are accessed only in the
+// application's main loop.
+type buffer struct {
+	name       string
+	checkpoint *checkpoint.Checkpoint
+
+	// text and cursor keep the editor content while another buffer is shown
+	text   string
+	cursor int
+
+	lastResult *bigquery.Result
+	// script is the result of the last script run, whose statement results
+	// can be shown in turn as lastResult. nil if the last query is not a
+	// script.
+	script *bigquery.Result
+	// resultMessage is shown in the result pane instead of lastResult if
+	// showTable is false
+	resultMessage string
+	showTable     bool
+
+	// stream fetches the rest of lastResult
+	stream       *bigquery.RowStream
+	cancelStream context.CancelFunc
+	// fetching is true while rows are being fetched from stream
+	fetching bool
+	// fetchErr is the error of fetching rows while another buffer is shown.
+	// It is reported when the user switches back.
+	fetchErr error
+}
+
+// loadBuffers reads buffers saved in the checkpoint directory. A buffer is
+// created if there is none.
+func (q *Query) loadBuffers() {
+	names, err := q.checkpoints.Names()
+	if err != nil {
+		// start with an empty buffer rather than failing to open the page
+		q.statusTextView.SetText(fmt.Sprintf("cannot list buffers: %s", err)).SetTextStyle(textStyleError)
+		names = nil
+	}
+
+	for _, name := range names {
+		cp := q.checkpoints.Checkpoint(name)
+
+		text, err := cp.Load()
+		if err != nil {
+			// ignore error
+			text = ""
+		}
+
+		q.buffers = append(q.buffers, &buffer{
+			name:       name,
+			checkpoint: cp,
+			text:       text,
+		})
+	}
+
+	if len(q.buffers) == 0 {
+		q.buffers = append(q.buffers, q.newBuffer())
+	}
+
+	q.current = q.buffers[0]
+	q.textArea.SetText(q.current.text, false)
+	q.renderTabs()
+}
+
+// newBuffer creates an empty buffer with an unused name.
+func (q *Query) newBuffer() *buffer {
+	name := ""
+
+	for i := 1; ; i++ {
+		name = bufferNamePrefix + strconv.Itoa(i)
+
+		if q.bufferIndex(name) < 0 {
+			break
+		}
+	}
+
+	return &buffer{
+		name:       name,
+		checkpoint: q.checkpoints.Checkpoint(name),
+	}
+}
+
+// bufferIndex returns the index of the buffer with the given name, or -1.
+func (q *Query) bufferIndex(name string) int {
+	return slices.IndexFunc(q.buffers, func(b *buffer) bool {
+		return b.name == name
+	})
+}
+
+// openNewBuffer adds an empty buffer next to the current one and switches to
+// it.
+func (q *Query) openNewBuffer() {
+	b := q.newBuffer()
+
+	i := slices.Index(q.buffers, q.current)
+	q.buffers = slices.Insert(q.buffers, i+1, b)
+
+	q.switchBuffer(b)
+	q.saveBufferOrder()
+}
+
+// moveBuffer switches to the buffer delta tabs away from the current one.
+func (q *Query) moveBuffer(delta int) {
+	i := slices.Index(q.buffers, q.current)
+	n := len(q.buffers)
+
+	q.switchBuffer(q.buffers[((i+delta)%n+n)%n])
+}
+
+// switchBufferAt switches to the i-th (0-based) buffer if it exists.
+func (q *Query) switchBufferAt(i int) {
+	if i < 0 || i >= len(q.buffers) {
+		q.statusTextView.SetText(fmt.Sprintf("no buffer %d", i+1)).SetTextStyle(textStyleError)
+		return
+	}
+
+	q.switchBuffer(q.buffers[i])
+}
+
+// switchBuffer shows the given buffer in the editor and the result pane. The
+// buffer switched from is written to its checkpoint.
+func (q *Query) switchBuffer(b *buffer) {
+	if b == q.current {
+		return
+	}
+
+	q.saveEditorState()
+
+	prev := q.current
+	saveErr := prev.checkpoint.Flush(prev.text, time.Now())
+
+	q.current = b
+
+	q.textArea.SetText(b.text, false)
+	q.textArea.Select(b.cursor, b.cursor)
+
+	q.restoreResultPane(b)
+	q.renderTabs()
+
+	q.app.SetFocus(q.textArea)
+
+	if saveErr != nil {
+		q.statusTextView.SetText(fmt.Sprintf("cannot save buffer %s: %s", prev.name, saveErr)).SetTextStyle(textStyleError)
+		return
+	}
+
+	if b.fetchErr != nil {
+		q.statusTextView.
+			SetText(fmt.Sprintf("switched to %s, [ERROR] cannot fetch rows: %s", b.name, b.fetchErr)).
+			SetTextStyle(textStyleError)
+		b.fetchErr = nil
+
+		return
+	}
+
+	q.statusTextView.SetText(fmt.Sprintf("switched to %s", b.name)).SetTextStyle(textStyleDefault)
+}
+
+// saveBufferOrder records the order of tabs in the checkpoint directory, so
+// that buffers are restored in the same order.
+func (q *Query) saveBufferOrder() {
+	if err := q.checkpoints.SaveOrder(q.bufferNames()); err != nil {
+		q.statusTextView.SetText(fmt.Sprintf("cannot save buffer order: %s", err)).SetTextStyle(textStyleError)
+	}
+}
+
+// bufferNames returns names of buffers in the order of tabs.
+func (q *Query) bufferNames() []string {
+	names := make([]string, 0, len(q.buffers))
+
+	for _, b := range q.buffers {
+		names = append(names, b.name)
+	}
+
+	return names
+}
+
+// saveEditorState keeps the editor content in the current buffer.
+func (q *Query) saveEditorState() {
+	q.current.text = q.textArea.GetText()
+	_, q.current.cursor, _ = q.textArea.GetSelection()
+}
+
+// restoreResultPane shows the result or message kept in the buffer.
+func (q *Query) restoreResultPane(b *buffer) {
+	if b.showTable && b.lastResult != nil {
+		q.resultTable.SetResult(b.lastResult)
+		q.resultPages.SwitchToPage(resultPageTable)
+		q.schemaTextView.SetText(formatSchema(b.lastResult.Schema)).ScrollToBeginning()
+
+		return
+	}
+
+	q.resultTextView.SetText(b.resultMessage).ScrollToBeginning()
+	q.resultPages.SwitchToPage(resultPageMessage)
+	q.schemaTextView.SetText(formatSchema(nil))
+}
+
+// renderTabs shows buffer names in the tab strip, highlighting the current one.
+func (q *Query) renderTabs() {
+	var sb strings.Builder
+
+	current := ""
+
+	for i, b := range q.buffers {
+		region := strconv.Itoa(i)
+		if b == q.current {
+			current = region
+		}
+
+		fmt.Fprintf(&sb, `["%s"] %d:%s [""] `, region, i+1, tview.Escape(b.name))
+	}
+
+	q.tabsTextView.SetText(sb.String()).Highlight(current)
+}
+
+// confirmRenameBuffer asks a new name of the current buffer.
+func (q *Query) confirmRenameBuffer() {
+	if q.modalFunc == nil {
+		return
+	}
+
+	b := q.current
+
+	var closeModal func()
+
+	form := tview.NewForm().AddInputField("name", b.name, 32, nil, nil)
+
+	form.
+		AddButton(modalButtonRename, func() {
+			name := form.GetFormItem(0).(*tview.InputField).GetText()
+
+			closeModal()
+			q.renameBuffer(b, name)
+		}).
+		AddButton(modalButtonCancel, func() {
+			closeModal()
+		}).
+		SetCancelFunc(func() {
+			closeModal()
+		})
+
+	form.SetBorder(true).SetTitle("rename buffer")
+
+	closeModal = q.modalFunc(center(form, 50, 7))
+}
+
+// renameBuffer renames the buffer and its checkpoint.
+func (q *Query) renameBuffer(b *buffer, name string) {
+	if name == b.name {
+		return
+	}
+
+	if err := checkpoint.ValidateName(name); err != nil {
+		q.statusTextView.SetText(err.Error()).SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.bufferIndex(name) >= 0 {
+		q.statusTextView.SetText(fmt.Sprintf("buffer %q already exists", name)).SetTextStyle(textStyleError)
+		return
+	}
+
+	if err := q.checkpoints.Rename(b.name, name); err != nil {
+		q.statusTextView.SetText(fmt.Sprintf("cannot rename buffer: %s", err)).SetTextStyle(textStyleError)
+		return
+	}
+
+	b.name = name
+	b.checkpoint = q.checkpoints.Checkpoint(name)
+
+	q.renderTabs()
+	q.statusTextView.SetText(fmt.Sprintf("renamed buffer to %s", name)).SetTextStyle(textStyleSuceess)
+	q.saveBufferOrder()
+}
+
+// confirmCloseBuffer asks whether to close the current buffer, which discards
+// its query.
+func (q *Query) confirmCloseBuffer() {
+	if len(q.buffers) == 1 {
+		q.statusTextView.SetText("cannot close the last buffer").SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.modalFunc == nil {
+		return
+	}
+
+	b := q.current
+
+	var closeModal func()
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Close buffer %s?\nIts query is discarded.", b.name)).
+		AddButtons([]string{modalButtonClose, modalButtonCancel}).
+		SetDoneFunc(func(_ int, label string) {
+			closeModal()
+
+			if label == modalButtonClose {
+				q.closeBuffer(b)
+			}
+		})
+
+	closeModal = q.modalFunc(modal)
+}
+
+// closeBuffer removes the buffer and its checkpoint. The query running for the
+// buffer is cancelled.
+func (q *Query) closeBuffer(b *buffer) {
+	i := slices.Index(q.buffers, b)
+	if i < 0 || len(q.buffers) == 1 {
+		return
+	}
+
+	if err := q.checkpoints.Remove(b.name); err != nil {
+		q.statusTextView.SetText(fmt.Sprintf("cannot close buffer: %s", err)).SetTextStyle(textStyleError)
+		return
+	}
+
+	q.mu.Lock()
+	if q.queryBuffer == b {
+		q.cancelQuery()
+	}
+	q.mu.Unlock()
+
+	q.closeStream(b)
+	q.buffers = slices.Delete(q.buffers, i, i+1)
+
+	if b == q.current {
+		// show the neighbor without keeping the editor content in b
+		next := q.buffers[min(i, len(q.buffers)-1)]
+
+		q.current = next
+		q.textArea.SetText(next.text, false)
+		q.textArea.Select(next.cursor, next.cursor)
+		q.restoreResultPane(next)
+	}
+
+	q.renderTabs()
+	q.statusTextView.SetText(fmt.Sprintf("closed buffer %s", b.name)).SetTextStyle(textStyleDefault)
+	q.saveBufferOrder()
+}
+
+// saveBuffers writes all buffers to their checkpoints, and their order.
+func (q *Query) saveBuffers() error {
+	q.saveEditorState()
+
+	for _, b := range q.buffers {
+		if err := b.checkpoint.Flush(b.text, time.Now()); err != nil {
+			return fmt.Errorf("save buffer %s: %w", b.name, err)
+		}
+	}
+
+	if err := q.checkpoints.SaveOrder(q.bufferNames()); err != nil {
+		return fmt.Errorf("save buffer order: %w", err)
+	}
+
+	return nil
+}
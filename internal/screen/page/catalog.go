@@ -0,0 +1,431 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	catalogTreeWidth = 40
+	// catalogPreviewRows is the number of rows read to preview a table
+	catalogPreviewRows = 100
+)
+
+// Catalog is the page to browse datasets and tables in the project.
+type Catalog struct {
+	*tview.Grid
+
+	app *tview.Application
+	ctx context.Context
+
+	backend bigquery.CatalogBackend
+
+	tree            *tview.TreeView
+	detailsTextView *tview.TextView
+	statusTextView  *tview.TextView
+
+	// loaded is true after datasets are listed for the first time
+	loaded bool
+
+	insertFunc  func(name string)
+	previewFunc func(name string, r *bigquery.Result)
+	doneFunc    func()
+}
+
+var _ Page = (*Catalog)(nil)
+
+// catalogNode is the reference of a node in the tree. Fields are accessed only
+// in the application's main loop.
+type catalogNode struct {
+	datasetID string
+	// tableID is empty for datasets
+	tableID string
+
+	// loading is true while tables of the dataset or details of the table are
+	// being fetched
+	loading bool
+	// loaded is true after tables of the dataset are listed
+	loaded bool
+	// table is the details of the table, fetched when it is selected first
+	table *bigquery.Table
+}
+
+// NewCatalog creates Catalog page.
+//
+// +-------------------------------------------------------------------+
+// | tree (width: 40)  | detailsTextView                               |
+// |                   |                                               |
+// |                   |                                               |
+// +-------------------------------------------------------------------+
+// | statusTextView (height: 1)                                        |
+// +-------------------------------------------------------------------+
+func NewCatalog(
+	app *tview.Application,
+	backend bigquery.CatalogBackend,
+) *Catalog {
+	c := &Catalog{
+		Grid: tview.NewGrid(),
+
+		app: app,
+
+		backend: backend,
+
+		tree:            tview.NewTreeView(),
+		detailsTextView: tview.NewTextView(),
+		statusTextView:  tview.NewTextView(),
+	}
+
+	c.SetRows(0, 1)
+	c.SetColumns(catalogTreeWidth, 0)
+
+	c.AddItem(c.tree, 0, 0, 1, 1, 0, 0, true)
+	c.AddItem(c.detailsTextView, 0, 1, 1, 1, 0, 0, false)
+	c.AddItem(c.statusTextView, 1, 0, 1, 2, 0, 0, false)
+
+	return c
+}
+
+// SetInsertFunc sets the handler called when the user inserts the name of the
+// selected table into the query.
+func (c *Catalog) SetInsertFunc(handler func(name string)) *Catalog {
+	c.insertFunc = handler
+	return c
+}
+
+// SetPreviewFunc sets the handler called with rows of the selected table read
+// for preview.
+func (c *Catalog) SetPreviewFunc(handler func(name string, r *bigquery.Result)) *Catalog {
+	c.previewFunc = handler
+	return c
+}
+
+// SetDoneFunc sets the handler called when the user leaves this page.
+func (c *Catalog) SetDoneFunc(handler func()) *Catalog {
+	c.doneFunc = handler
+	return c
+}
+
+func (c *Catalog) Init(ctx context.Context) error {
+	c.ctx = ctx
+
+	root := tview.NewTreeNode(c.backend.ProjectID()).
+		SetColor(tcell.ColorYellow).
+		SetSelectable(false)
+
+	c.tree.
+		SetRoot(root).
+		SetTopLevel(1).
+		SetGraphics(true).
+		SetSelectedFunc(func(node *tview.TreeNode) {
+			c.expand(node)
+		}).
+		SetChangedFunc(func(node *tview.TreeNode) {
+			c.showDetails(node)
+		})
+	c.tree.SetBorder(true).SetTitle("datasets")
+
+	c.detailsTextView.
+		SetTextStyle(textStyleDefault).
+		SetWordWrap(false).
+		SetBorder(true).
+		SetTitle("details")
+
+	c.statusTextView.SetTextStyle(textStyleDefault)
+
+	c.bindKeys()
+
+	return nil
+}
+
+func (c *Catalog) Close() error {
+	return nil
+}
+
+// Open lists datasets when the page is opened for the first time. The tree is
+// kept afterwards until the user reloads it.
+func (c *Catalog) Open() {
+	if !c.loaded {
+		c.Reload()
+	}
+
+	c.app.SetFocus(c.tree)
+}
+
+// Reload lists datasets again, collapsing the tree.
+func (c *Catalog) Reload() {
+	c.loaded = true
+
+	root := c.tree.GetRoot()
+	root.ClearChildren()
+	c.detailsTextView.SetText("")
+	c.statusTextView.SetText("loading datasets...").SetTextStyle(textStyleDefault)
+
+	go func() {
+		ids, err := c.backend.ListDatasets(c.ctx)
+
+		c.app.QueueUpdateDraw(func() {
+			if err != nil {
+				c.loaded = false
+				c.statusTextView.SetText(fmt.Sprintf("cannot list datasets: %s", err)).SetTextStyle(textStyleError)
+
+				return
+			}
+
+			root.ClearChildren()
+
+			for _, id := range ids {
+				root.AddChild(tview.NewTreeNode(id).
+					SetReference(&catalogNode{datasetID: id}).
+					SetColor(tcell.ColorDodgerBlue))
+			}
+
+			if children := root.GetChildren(); len(children) > 0 {
+				// SetCurrentNode does not call the changed handler
+				c.tree.SetCurrentNode(children[0])
+				c.showDetails(children[0])
+			}
+
+			c.statusTextView.SetText(catalogHelp).SetTextStyle(textStyleDefault)
+		})
+	}()
+}
+
+const catalogHelp = "Enter: expand dataset, i: insert table name, p: preview table, r: reload, Esc: back"
+
+func (c *Catalog) bindKeys() {
+	c.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyCtrlG:
+			if c.doneFunc != nil {
+				c.doneFunc()
+			}
+
+			return nil
+
+		case tcell.KeyCtrlN:
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+
+		case tcell.KeyCtrlP:
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'i':
+				c.insertSelectedName()
+
+				return nil
+
+			case 'p':
+				c.previewSelectedTable()
+
+				return nil
+
+			case 'r':
+				c.Reload()
+
+				return nil
+			}
+		}
+
+		return event
+	})
+}
+
+// expand toggles the dataset node, listing its tables for the first time.
+func (c *Catalog) expand(node *tview.TreeNode) {
+	ref, ok := node.GetReference().(*catalogNode)
+	if !ok || ref.tableID != "" {
+		return
+	}
+
+	if ref.loaded || ref.loading {
+		node.SetExpanded(!node.IsExpanded())
+		return
+	}
+
+	ref.loading = true
+	c.statusTextView.SetText(fmt.Sprintf("loading tables in %s...", ref.datasetID)).SetTextStyle(textStyleDefault)
+
+	go func() {
+		ids, err := c.backend.ListTables(c.ctx, ref.datasetID)
+
+		c.app.QueueUpdateDraw(func() {
+			ref.loading = false
+
+			if err != nil {
+				c.statusTextView.SetText(fmt.Sprintf("cannot list tables: %s", err)).SetTextStyle(textStyleError)
+				return
+			}
+
+			ref.loaded = true
+
+			node.ClearChildren()
+
+			for _, id := range ids {
+				node.AddChild(tview.NewTreeNode(id).
+					SetReference(&catalogNode{datasetID: ref.datasetID, tableID: id}))
+			}
+
+			node.SetExpanded(true)
+
+			c.statusTextView.SetText(fmt.Sprintf("%d table(s) in %s", len(ids), ref.datasetID)).SetTextStyle(textStyleDefault)
+		})
+	}()
+}
+
+// showDetails shows the details of the table node, fetching them for the first
+// time.
+func (c *Catalog) showDetails(node *tview.TreeNode) {
+	ref, ok := node.GetReference().(*catalogNode)
+	if !ok {
+		return
+	}
+
+	if ref.tableID == "" {
+		c.detailsTextView.SetText(fmt.Sprintf("dataset %s.%s", c.backend.ProjectID(), ref.datasetID))
+		return
+	}
+
+	if ref.table != nil {
+		c.detailsTextView.SetText(formatTable(ref.table)).ScrollToBeginning()
+		return
+	}
+
+	c.detailsTextView.SetText("loading...")
+
+	if ref.loading {
+		return
+	}
+
+	ref.loading = true
+
+	go func() {
+		t, err := c.backend.GetTable(c.ctx, ref.datasetID, ref.tableID)
+
+		c.app.QueueUpdateDraw(func() {
+			ref.loading = false
+
+			if err != nil {
+				c.statusTextView.SetText(fmt.Sprintf("cannot get table: %s", err)).SetTextStyle(textStyleError)
+				return
+			}
+
+			ref.table = t
+
+			// the user may have moved to another node while fetching
+			if c.tree.GetCurrentNode() == node {
+				c.detailsTextView.SetText(formatTable(t)).ScrollToBeginning()
+			}
+		})
+	}()
+}
+
+// insertSelectedName passes the fully qualified name of the selected table to
+// the insert handler.
+func (c *Catalog) insertSelectedName() {
+	node := c.tree.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	ref, ok := node.GetReference().(*catalogNode)
+	if !ok {
+		return
+	}
+
+	name := ref.datasetID
+	if ref.tableID != "" {
+		name += "." + ref.tableID
+	}
+
+	if c.insertFunc != nil {
+		c.insertFunc(fmt.Sprintf("`%s.%s`", c.backend.ProjectID(), name))
+	}
+}
+
+// previewSelectedTable reads the first rows of the selected table and passes
+// them to the preview handler.
+func (c *Catalog) previewSelectedTable() {
+	node := c.tree.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	ref, ok := node.GetReference().(*catalogNode)
+	if !ok || ref.tableID == "" {
+		c.statusTextView.SetText("select a table to preview").SetTextStyle(textStyleError)
+		return
+	}
+
+	name := fmt.Sprintf("%s.%s.%s", c.backend.ProjectID(), ref.datasetID, ref.tableID)
+	c.statusTextView.SetText(fmt.Sprintf("reading %s...", name)).SetTextStyle(textStyleDefault)
+
+	go func() {
+		r, err := c.backend.PreviewTable(c.ctx, ref.datasetID, ref.tableID, catalogPreviewRows)
+
+		c.app.QueueUpdateDraw(func() {
+			if err != nil {
+				c.statusTextView.SetText(fmt.Sprintf("cannot preview table: %s", err)).SetTextStyle(textStyleError)
+				return
+			}
+
+			c.statusTextView.SetText(catalogHelp).SetTextStyle(textStyleDefault)
+
+			if c.previewFunc != nil {
+				c.previewFunc(name, r)
+			}
+		})
+	}()
+}
+
+// formatTable describes the table and its schema.
+func formatTable(t *bigquery.Table) string {
+	var b strings.Builder
+
+	fmt.Fprintln(&b, t.FullName())
+	fmt.Fprintln(&b)
+
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+
+	optional := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+
+		return s
+	}
+
+	fmt.Fprintf(w, "type\t%s\n", optional(t.Type))
+	fmt.Fprintf(w, "rows\t%s\n", humanize.Comma(int64(t.NumRows)))
+	fmt.Fprintf(w, "size\t%s\n", humanize.Bytes(uint64(t.NumBytes)))
+	fmt.Fprintf(w, "partitioning\t%s\n", optional(t.Partitioning))
+	fmt.Fprintf(w, "clustering\t%s\n", optional(strings.Join(t.Clustering, ", ")))
+
+	if !t.CreationTime.IsZero() {
+		fmt.Fprintf(w, "created\t%s\n", t.CreationTime.Local().Format(historyTimeFormat))
+	}
+
+	if !t.LastModifiedTime.IsZero() {
+		fmt.Fprintf(w, "last modified\t%s\n", t.LastModifiedTime.Local().Format(historyTimeFormat))
+	}
+
+	if t.Description != "" {
+		fmt.Fprintf(w, "description\t%s\n", t.Description)
+	}
+
+	w.Flush()
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "schema:")
+	b.WriteString(formatSchema(t.Schema))
+
+	return b.String()
+}
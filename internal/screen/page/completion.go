@@ -0,0 +1,422 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/googlesql"
+)
+
+const (
+	// completionHeight is the maximum number of candidates shown at once
+	completionHeight = 8
+	// completionMaxCandidates limits candidates to keep the popup responsive
+	completionMaxCandidates = 200
+)
+
+// candidate is a name offered by the completion popup.
+type candidate struct {
+	text string
+	// kind is shown next to the text, e.g. "keyword" or "column"
+	kind string
+}
+
+// completionPopup is the list of candidates shown below the cursor.
+type completionPopup struct {
+	candidates []candidate
+	selected   int
+	// top is the index of the first candidate shown in the popup
+	top int
+
+	// start and prefix are where the name being completed starts and what is
+	// typed so far
+	start  int
+	prefix string
+
+	// explicit is true if the popup is opened by Ctrl-Space. Enter accepts a
+	// candidate only then, so that it inserts a newline while typing.
+	explicit bool
+}
+
+func (p *completionPopup) move(delta int) {
+	n := len(p.candidates)
+	p.selected = ((p.selected+delta)%n + n) % n
+
+	if p.selected < p.top {
+		p.top = p.selected
+	}
+
+	if p.selected >= p.top+completionHeight {
+		p.top = p.selected - completionHeight + 1
+	}
+}
+
+// handleCompletionKey handles keys for the completion popup. It returns nil if
+// the key is consumed.
+func (q *Query) handleCompletionKey(event *tcell.EventKey) *tcell.EventKey {
+	if q.completion == nil {
+		switch event.Key() {
+		case tcell.KeyCtrlSpace:
+			q.completionTrigger = true
+			q.updateCompletion(true)
+
+			return nil
+
+		case tcell.KeyRune:
+			q.completionTrigger = true
+		}
+
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		q.completion.move(1)
+
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		q.completion.move(-1)
+
+	case tcell.KeyTab:
+		q.acceptCompletion()
+
+	case tcell.KeyEnter:
+		if !q.completion.explicit {
+			q.closeCompletion()
+
+			return event
+		}
+
+		q.acceptCompletion()
+
+	case tcell.KeyEscape, tcell.KeyCtrlG:
+		q.closeCompletion()
+
+	case tcell.KeyRune, tcell.KeyBackspace, tcell.KeyBackspace2:
+		// the candidates are narrowed after the text is changed
+		q.completionTrigger = true
+
+		return event
+
+	default:
+		q.closeCompletion()
+
+		return event
+	}
+
+	return nil
+}
+
+// onTextChanged updates the completion popup if the text is changed by typing,
+// and closes it otherwise.
+func (q *Query) onTextChanged() {
+	if !q.completionTrigger {
+		q.closeCompletion()
+		return
+	}
+
+	q.completionTrigger = false
+	q.updateCompletion(false)
+}
+
+// updateCompletion shows candidates for the name at the cursor. Unless
+// explicit, the popup is shown only while a name is being typed, or right
+// after FROM or ".".
+func (q *Query) updateCompletion(explicit bool) {
+	// the popup narrowed by typing stays explicit
+	explicit = explicit || q.completion != nil && q.completion.explicit
+
+	text := q.textArea.GetText()
+	_, offset, _ := q.textArea.GetSelection()
+
+	q.completionAt = offset
+
+	c := googlesql.CompletionAt(text, offset)
+
+	if c.Kind == googlesql.CompleteNone || !explicit && c.Prefix == "" && c.Kind == googlesql.CompleteAny {
+		q.closeCompletion()
+		return
+	}
+
+	cands := filterCandidates(q.completionCandidates(c, explicit), c.Prefix)
+
+	// nothing to complete if the name is already typed
+	if len(cands) == 0 || len(cands) == 1 && strings.EqualFold(cands[0].text, c.Prefix) {
+		q.closeCompletion()
+		return
+	}
+
+	q.completion = &completionPopup{
+		candidates: cands,
+		start:      c.Start,
+		prefix:     c.Prefix,
+		explicit:   explicit,
+	}
+}
+
+func (q *Query) closeCompletion() {
+	q.completion = nil
+}
+
+// acceptCompletion replaces the name being typed with the selected candidate.
+func (q *Query) acceptCompletion() {
+	p := q.completion
+	q.closeCompletion()
+
+	_, offset, _ := q.textArea.GetSelection()
+
+	c := p.candidates[p.selected]
+
+	text := c.text
+	if (c.kind == "keyword" || c.kind == "function") && p.prefix != "" && p.prefix == strings.ToLower(p.prefix) {
+		text = strings.ToLower(text)
+	}
+
+	q.textArea.Replace(p.start, offset, text)
+}
+
+// completionCandidates returns names which can be completed. Names not cached
+// yet are fetched in background, and the popup is updated when they arrive.
+// Failures to fetch them are reported only if explicit.
+func (q *Query) completionCandidates(c googlesql.Completion, explicit bool) []candidate {
+	cands := []candidate{}
+
+	switch c.Kind {
+	case googlesql.CompleteAny:
+		for _, r := range c.Tables {
+			for _, f := range q.catalogColumns(r.Path, explicit) {
+				cands = append(cands, candidate{text: f.Name, kind: "column"})
+			}
+		}
+
+		for _, k := range googlesql.Keywords() {
+			cands = append(cands, candidate{text: k, kind: "keyword"})
+		}
+
+		for _, f := range googlesql.Functions() {
+			cands = append(cands, candidate{text: f, kind: "function"})
+		}
+
+	case googlesql.CompleteTable:
+		if q.catalog == nil {
+			break
+		}
+
+		dataset, ok := strings.CutPrefix(c.Qualifier, q.catalog.ProjectID()+".")
+		if !ok && c.Qualifier == q.catalog.ProjectID() {
+			dataset = ""
+		}
+
+		if dataset == "" {
+			for _, d := range q.catalogDatasets(explicit) {
+				cands = append(cands, candidate{text: d, kind: "dataset"})
+			}
+		} else if !strings.Contains(dataset, ".") {
+			for _, t := range q.catalogTables(dataset, explicit) {
+				cands = append(cands, candidate{text: t, kind: "table"})
+			}
+		}
+
+	case googlesql.CompleteColumn:
+		if c.Table == nil {
+			break
+		}
+
+		for _, f := range q.catalogColumns(c.Table.Path, explicit) {
+			cands = append(cands, candidate{text: f.Name, kind: "column"})
+		}
+	}
+
+	return cands
+}
+
+// filterCandidates returns candidates starting with prefix case-insensitively,
+// without duplicates.
+func filterCandidates(cands []candidate, prefix string) []candidate {
+	filtered := []candidate{}
+	seen := map[string]struct{}{}
+
+	for _, c := range cands {
+		if len(c.text) < len(prefix) || !strings.EqualFold(c.text[:len(prefix)], prefix) {
+			continue
+		}
+
+		if _, ok := seen[c.text]; ok {
+			continue
+		}
+
+		seen[c.text] = struct{}{}
+		filtered = append(filtered, c)
+
+		if len(filtered) >= completionMaxCandidates {
+			break
+		}
+	}
+
+	return filtered
+}
+
+func (q *Query) catalogDatasets(explicit bool) []string {
+	ids, fresh := q.catalog.CachedDatasets()
+	if !fresh {
+		q.fetchCatalog(explicit, func(ctx context.Context) error {
+			_, err := q.catalog.FetchDatasets(ctx)
+			return err
+		})
+	}
+
+	return ids
+}
+
+func (q *Query) catalogTables(datasetID string, explicit bool) []string {
+	ids, fresh := q.catalog.CachedTables(datasetID)
+	if !fresh {
+		q.fetchCatalog(explicit, func(ctx context.Context) error {
+			_, err := q.catalog.FetchTables(ctx, datasetID)
+			return err
+		})
+	}
+
+	return ids
+}
+
+// catalogColumns returns columns of the table at the given path, which is
+// dataset.table or project.dataset.table in the current project.
+func (q *Query) catalogColumns(path []string, explicit bool) []*bigquery.Field {
+	if q.catalog == nil {
+		return nil
+	}
+
+	switch {
+	case len(path) == 3 && path[0] == q.catalog.ProjectID():
+		path = path[1:]
+	case len(path) != 2:
+		return nil
+	}
+
+	datasetID, tableID := path[0], path[1]
+
+	fields, fresh := q.catalog.CachedColumns(datasetID, tableID)
+	if !fresh {
+		q.fetchCatalog(explicit, func(ctx context.Context) error {
+			_, err := q.catalog.FetchColumns(ctx, datasetID, tableID)
+			return err
+		})
+	}
+
+	return fields
+}
+
+// fetchCatalog runs f in background, and updates the completion popup if the
+// cursor is still where the completion was requested. A failure is reported
+// only if the completion is explicitly requested, since names referenced while
+// typing may not exist yet, e.g. CTEs or tables created by the script.
+func (q *Query) fetchCatalog(explicit bool, f func(ctx context.Context) error) {
+	at := q.completionAt
+
+	go func() {
+		err := f(q.ctx)
+
+		q.app.QueueUpdateDraw(func() {
+			if err != nil {
+				if explicit {
+					q.statusTextView.SetText(fmt.Sprintf("cannot fetch names for completion: %s", err)).SetTextStyle(textStyleWarning)
+				}
+
+				return
+			}
+
+			// the user may have moved on while fetching
+			if _, offset, _ := q.textArea.GetSelection(); offset == at && q.completionAt == at && q.textArea.HasFocus() {
+				q.updateCompletion(explicit)
+			}
+		})
+	}()
+}
+
+// drawCompletion draws the popup below the name being completed, or above it
+// if there is no space below.
+func (q *Query) drawCompletion(screen tcell.Screen) {
+	p := q.completion
+	if p == nil {
+		return
+	}
+
+	_, _, row, column := q.textArea.GetCursor()
+	rowOffset, columnOffset := q.textArea.GetOffset()
+	ex, ey, _, _ := q.textArea.GetInnerRect()
+	// the popup is kept inside the page, whose position is absolute like ex
+	// and ey
+	px, py, pw, ph := q.GetRect()
+
+	textWidth := 0
+	kindWidth := 0
+
+	for _, c := range p.candidates {
+		textWidth = max(textWidth, uniseg.StringWidth(c.text))
+		kindWidth = max(kindWidth, len(c.kind))
+	}
+
+	width := min(textWidth+kindWidth+3, pw)
+	height := min(len(p.candidates), completionHeight, ph)
+
+	x := ex + column - columnOffset - uniseg.StringWidth(p.prefix)
+	x = max(px, min(x, px+pw-width))
+
+	y := ey + row - rowOffset + 1
+	if y+height > py+ph {
+		y = max(py, ey+row-rowOffset-height)
+	}
+
+	for i := 0; i < height && p.top+i < len(p.candidates); i++ {
+		c := p.candidates[p.top+i]
+
+		style := completionStyle
+		if p.top+i == p.selected {
+			style = completionSelectedStyle
+		}
+
+		for cx := x; cx < x+width; cx++ {
+			screen.SetContent(cx, y+i, ' ', nil, style)
+		}
+
+		printText(screen, c.text, x+1, y+i, textWidth, style)
+		printText(screen, c.kind, x+textWidth+2, y+i, kindWidth, style.Dim(true))
+	}
+}
+
+// Draw draws the page, and the completion popup over it.
+func (q *Query) Draw(screen tcell.Screen) {
+	q.Grid.Draw(screen)
+	q.drawCompletion(screen)
+}
+
+// printText draws text as is, without interpreting style tags, cutting it at
+// maxWidth.
+func printText(screen tcell.Screen, text string, x, y, maxWidth int, style tcell.Style) {
+	state := -1
+	column := 0
+
+	for text != "" {
+		var (
+			cluster    string
+			boundaries int
+		)
+
+		cluster, text, boundaries, state = uniseg.StepString(text, state)
+
+		w := boundaries >> uniseg.ShiftWidth
+		if column+w > maxWidth {
+			return
+		}
+
+		runes := []rune(cluster)
+		screen.SetContent(x+column, y, runes[0], runes[1:], style)
+
+		column += w
+	}
+}
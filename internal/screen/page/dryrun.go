@@ -0,0 +1,134 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/googlesql"
+)
+
+// scheduleLiveDryRun dry-runs the statement at the cursor once typing stops for
+// LiveDryRunDelay. Every change cancels the dry run scheduled or running
+// before, so that only the latest text is reported.
+func (q *Query) scheduleLiveDryRun() {
+	if q.opts.LiveDryRunDelay <= 0 {
+		return
+	}
+
+	q.liveDryRunSeq++
+	seq := q.liveDryRunSeq
+
+	if q.liveDryRunTimer != nil {
+		q.liveDryRunTimer.Stop()
+	}
+
+	if q.cancelLiveDryRun != nil {
+		q.cancelLiveDryRun()
+		q.cancelLiveDryRun = nil
+	}
+
+	q.liveDryRunTimer = time.AfterFunc(q.opts.LiveDryRunDelay, func() {
+		q.app.QueueUpdate(func() {
+			q.startLiveDryRun(seq)
+		})
+	})
+}
+
+// startLiveDryRun dry-runs the statement at the cursor in background, unless
+// the text has changed since seq was scheduled.
+func (q *Query) startLiveDryRun(seq int) {
+	if seq != q.liveDryRunSeq {
+		return
+	}
+
+	text := q.textArea.GetText()
+	_, cursor, _ := q.textArea.GetSelection()
+
+	stmt, ok := googlesql.StatementAt(text, cursor)
+	if !ok {
+		return
+	}
+
+	// parameters are not asked while typing, so the last ones are used
+	params := q.parameters[stmt.Text]
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	q.cancelLiveDryRun = cancel
+
+	go func() {
+		defer cancel()
+
+		r, err := q.bqClient.DryRunQuery(ctx, stmt.Text, params...)
+
+		// a canceled dry run is stale, so nothing is reported
+		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			return
+		}
+
+		q.app.QueueUpdateDraw(func() {
+			if seq != q.liveDryRunSeq || q.isQueryRunning() {
+				return
+			}
+
+			q.cancelLiveDryRun = nil
+
+			if err != nil {
+				q.statusTextView.
+					SetText("[DRY RUN] " + liveDryRunError(text, stmt.Start, err)).
+					SetTextStyle(textStyleError)
+
+				return
+			}
+
+			q.statusTextView.
+				SetText(
+					fmt.Sprintf(
+						"[DRY RUN] this statement will process %s of data (about $%.2f)",
+						humanize.Bytes(uint64(r.TotalBytesProcessed)),
+						estimateCost(r.TotalBytesProcessed, q.opts.PricePerTiB),
+					),
+				).
+				SetTextStyle(textStyleDefault)
+		})
+	}()
+}
+
+// isQueryRunning reports whether a query started by runQuery is running, whose
+// progress must not be overwritten.
+func (q *Query) isQueryRunning() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.cancelQuery != nil
+}
+
+// liveDryRunError describes the first validation error of the statement at
+// offset in text. The position reported by BigQuery is translated to the one in
+// the editor.
+func liveDryRunError(text string, offset int, err error) string {
+	var qe *bigquery.QueryError
+	if !errors.As(err, &qe) {
+		return err.Error()
+	}
+
+	if !qe.HasPosition() {
+		return qe.Message
+	}
+
+	before := text[:offset]
+	line := strings.Count(before, "\n") + qe.Line
+	column := qe.Column
+
+	if qe.Line == 1 {
+		column += utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:])
+	}
+
+	return strings.Replace(qe.Message, fmt.Sprintf("[%d:%d]", qe.Line, qe.Column), fmt.Sprintf("[%d:%d]", line, column), 1)
+}
@@ -0,0 +1,71 @@
+package page
+
+import (
+	"errors"
+	"testing"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+func TestLiveDryRunError(t *testing.T) {
+	t.Parallel()
+
+	unrecognized := bigquery.NewQueryError(&bigqueryapi.Error{
+		Reason:  "invalidQuery",
+		Message: "Unrecognized name: foo at [1:8]",
+	})
+
+	testcases := map[string]struct {
+		text   string
+		offset int
+		err    error
+		want   string
+	}{
+		"first statement": {
+			text:   "select foo",
+			offset: 0,
+			err:    unrecognized,
+			want:   "Unrecognized name: foo at [1:8]",
+		},
+		"statement on the same line": {
+			text:   "select 'あ'; select foo",
+			offset: 14,
+			err:    unrecognized,
+			want:   "Unrecognized name: foo at [1:20]",
+		},
+		"statement on another line": {
+			text:   "select 1;\n\nselect foo",
+			offset: 11,
+			err:    unrecognized,
+			want:   "Unrecognized name: foo at [3:8]",
+		},
+		"no position": {
+			text:   "select foo",
+			offset: 0,
+			err: bigquery.NewQueryError(&bigqueryapi.Error{
+				Reason:  "accessDenied",
+				Message: "Access Denied",
+			}),
+			want: "Access Denied",
+		},
+		"other error": {
+			text:   "select foo",
+			offset: 0,
+			err:    errors.New("connection refused"),
+			want:   "connection refused",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := liveDryRunError(tc.text, tc.offset, tc.err); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
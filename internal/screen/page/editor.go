@@ -0,0 +1,212 @@
+package page
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
+
+	"github.com/dtan4/bqc/internal/googlesql"
+)
+
+// editor is the query editor. It highlights the syntax of the query, and can
+// highlight a line, e.g. where BigQuery reported an error.
+type editor struct {
+	*tview.TextArea
+
+	// errorLine is the 0-based line highlighted as an error. -1 if none.
+	errorLine int
+
+	changed func()
+
+	// text, tokens and lineStarts are the text split for syntax highlighting.
+	// They are updated on the next Draw after the text is changed, so that
+	// typing in a long query tokenizes it only once per frame.
+	text       string
+	tokens     []googlesql.Token
+	lineStarts []int
+	tokenized  bool
+}
+
+func newEditor() *editor {
+	e := &editor{
+		TextArea:  tview.NewTextArea(),
+		errorLine: -1,
+	}
+
+	// lines are not wrapped so that a line of the text is a row on the screen
+	e.SetWrap(false)
+	e.TextArea.SetChangedFunc(func() {
+		e.errorLine = -1
+		e.tokenized = false
+
+		if e.changed != nil {
+			e.changed()
+		}
+	})
+
+	return e
+}
+
+// SetChangedFunc sets the handler called when the text is changed.
+func (e *editor) SetChangedFunc(handler func()) *editor {
+	e.changed = handler
+	return e
+}
+
+// ShowError moves the cursor to the given 1-based line and column, and
+// highlights the line until the text is modified. The position is relative to
+// the given byte offset, where the query with the error starts.
+func (e *editor) ShowError(offset, line, column int) {
+	text := e.GetText()
+	if offset > len(text) {
+		offset = len(text)
+	}
+
+	pos := offset + textOffset(text[offset:], line-1, column-1)
+
+	e.Select(pos, pos)
+	e.errorLine = strings.Count(text[:pos], "\n")
+}
+
+func (e *editor) Draw(screen tcell.Screen) {
+	e.TextArea.Draw(screen)
+
+	e.drawSyntax(screen)
+	e.drawErrorLine(screen)
+}
+
+// drawSyntax restyles the visible text drawn by TextArea with the style of
+// each token. Only rows on the screen are visited, so the cost of drawing does
+// not grow with the length of the query.
+func (e *editor) drawSyntax(screen tcell.Screen) {
+	if !e.tokenized {
+		e.text = e.GetText()
+		e.tokens = googlesql.Tokenize(e.text)
+		e.lineStarts = lineStarts(e.text)
+		e.tokenized = true
+	}
+
+	if e.text == "" {
+		return
+	}
+
+	rowOffset, columnOffset := e.GetOffset()
+	x, y, width, height := e.GetInnerRect()
+	textStyle := e.GetTextStyle()
+
+	for row := 0; row < height && rowOffset+row < len(e.lineStarts); row++ {
+		line := rowOffset + row
+
+		pos := e.lineStarts[line]
+		end := len(e.text)
+		if line+1 < len(e.lineStarts) {
+			end = e.lineStarts[line+1] - 1
+		}
+
+		// the first token which is not before the line
+		ti := sort.Search(len(e.tokens), func(i int) bool {
+			return e.tokens[i].End > pos
+		})
+
+		// column is the screen column of the cluster from the line head, laid
+		// out in the same way as TextArea
+		column := 0
+		rest := e.text[pos:end]
+		state := -1
+
+		for rest != "" && column-columnOffset < width {
+			var (
+				cluster    string
+				boundaries int
+			)
+
+			cluster, rest, boundaries, state = uniseg.StepString(rest, state)
+
+			w := boundaries >> uniseg.ShiftWidth
+			if cluster == "\t" {
+				w = tview.TabSize
+			}
+
+			for ti < len(e.tokens) && e.tokens[ti].End <= pos {
+				ti++
+			}
+
+			if style, ok := syntaxStyles[e.tokens[ti].Kind]; ok && column >= columnOffset && w > 0 && column+w-columnOffset <= width {
+				cx := x + column - columnOffset
+
+				// selected text keeps the selection style
+				if str, cellStyle, _ := screen.Get(cx, y+row); cellStyle == textStyle {
+					screen.Put(cx, y+row, str, style)
+				}
+			}
+
+			pos += len(cluster)
+			column += w
+		}
+	}
+}
+
+// drawErrorLine paints the background of the error line.
+func (e *editor) drawErrorLine(screen tcell.Screen) {
+	if e.errorLine < 0 {
+		return
+	}
+
+	rowOffset, _ := e.GetOffset()
+	x, y, width, height := e.GetInnerRect()
+
+	row := e.errorLine - rowOffset
+	if row < 0 || row >= height {
+		return
+	}
+
+	for cx := x; cx < x+width; {
+		str, style, w := screen.Get(cx, y+row)
+		if str == "" {
+			str = " "
+		}
+
+		screen.Put(cx, y+row, str, style.Background(lineBackgroundError))
+
+		cx += max(w, 1)
+	}
+}
+
+// lineStarts returns byte offsets where lines of text start.
+func lineStarts(text string) []int {
+	starts := []int{0}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+
+	return starts
+}
+
+// textOffset returns the byte offset of the given 0-based line and column in
+// text. The position is clamped to the text.
+func textOffset(text string, line, column int) int {
+	offset := 0
+
+	for i := 0; i < line; i++ {
+		n := strings.IndexByte(text[offset:], '\n')
+		if n < 0 {
+			return len(text)
+		}
+
+		offset += n + 1
+	}
+
+	for i := 0; i < column && offset < len(text) && text[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+
+	return offset
+}
@@ -0,0 +1,352 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/history"
+)
+
+const (
+	historyTimeFormat = "2006-01-02 15:04:05"
+)
+
+type History struct {
+	*tview.Grid
+
+	app *tview.Application
+
+	history history.Storage
+
+	filterInputField *tview.InputField
+	table            *tview.Table
+	queryTextView    *tview.TextView
+	statusTextView   *tview.TextView
+
+	// entries holds all history entries, newest first
+	entries []*history.Entry
+	// filtered holds entries matching the current filter, in the same order as
+	// table rows (excluding the header)
+	filtered []*history.Entry
+	// reloadSeq identifies the latest Reload, so that entries loaded by older
+	// ones are discarded
+	reloadSeq int
+
+	loadFunc   func(query string)
+	renderFunc func(r *bigquery.Result)
+	doneFunc   func()
+}
+
+var _ Page = (*History)(nil)
+
+// NewHistory creates History page.
+//
+// +-------------------------------------------------------------------+
+// | filterInputField (height: 1)                                      |
+// +-------------------------------------------------------------------+
+// | table                                                             |
+// |                                                                   |
+// |                                                                   |
+// +-------------------------------------------------------------------+
+// | queryTextView (height: 10)                                        |
+// |                                                                   |
+// +-------------------------------------------------------------------+
+// | statusTextView (height: 1)                                        |
+// +-------------------------------------------------------------------+
+func NewHistory(
+	app *tview.Application,
+	history history.Storage,
+) *History {
+	h := &History{
+		Grid: tview.NewGrid(),
+
+		app: app,
+
+		history: history,
+
+		filterInputField: tview.NewInputField(),
+		table:            tview.NewTable(),
+		queryTextView:    tview.NewTextView(),
+		statusTextView:   tview.NewTextView(),
+	}
+
+	h.SetRows(1, 0, 10, 1)
+	h.SetColumns(0)
+
+	h.AddItem(h.filterInputField, 0, 0, 1, 1, 0, 0, true)
+	h.AddItem(h.table, 1, 0, 1, 1, 0, 0, false)
+	h.AddItem(h.queryTextView, 2, 0, 1, 1, 0, 0, false)
+	h.AddItem(h.statusTextView, 3, 0, 1, 1, 0, 0, false)
+
+	return h
+}
+
+// SetLoadFunc sets the handler called when the user loads the selected query
+// into the editor.
+func (h *History) SetLoadFunc(handler func(query string)) *History {
+	h.loadFunc = handler
+	return h
+}
+
+// SetRenderFunc sets the handler called when the user renders the stored
+// result of the selected query.
+func (h *History) SetRenderFunc(handler func(r *bigquery.Result)) *History {
+	h.renderFunc = handler
+	return h
+}
+
+// SetDoneFunc sets the handler called when the user leaves this page.
+func (h *History) SetDoneFunc(handler func()) *History {
+	h.doneFunc = handler
+	return h
+}
+
+func (h *History) Init(ctx context.Context) error {
+	h.filterInputField.
+		SetLabel("filter: ").
+		SetFieldStyle(textStyleDefault).
+		SetChangedFunc(func(text string) {
+			h.applyFilter(text)
+		})
+
+	h.table.
+		SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetSelectionChangedFunc(func(row, column int) {
+			h.showSelectedQuery()
+		})
+
+	h.queryTextView.
+		SetTextStyle(textStyleDefault).
+		SetWordWrap(false).
+		SetBorder(true).
+		SetTitle("query")
+
+	h.statusTextView.
+		SetTextStyle(textStyleDefault).
+		SetText(historyHelp)
+
+	h.bindKeys()
+
+	return nil
+}
+
+func (h *History) Close() error {
+	return nil
+}
+
+// Reload reads history entries from the storage again in background and
+// resets the filter. Errors are shown in the status bar.
+func (h *History) Reload() {
+	h.reloadSeq++
+	seq := h.reloadSeq
+
+	h.entries = nil
+	h.filterInputField.SetText("")
+	h.applyFilter("")
+	h.statusTextView.SetText("loading history...").SetTextStyle(textStyleDefault)
+
+	go func() {
+		es, err := h.history.List()
+
+		h.app.QueueUpdateDraw(func() {
+			if seq != h.reloadSeq {
+				return
+			}
+
+			if err != nil {
+				h.statusTextView.
+					SetText(fmt.Sprintf("cannot load history: %s", err)).
+					SetTextStyle(textStyleError)
+
+				return
+			}
+
+			// storage returns entries in chronological order
+			h.entries = make([]*history.Entry, 0, len(es))
+			for i := len(es) - 1; i >= 0; i-- {
+				h.entries = append(h.entries, es[i])
+			}
+
+			h.applyFilter(h.filterInputField.GetText())
+			h.statusTextView.SetText(historyHelp).SetTextStyle(textStyleDefault)
+		})
+	}()
+}
+
+const historyHelp = "Enter: load query, Ctrl-R: show stored result, Esc: back"
+
+func (h *History) bindKeys() {
+	h.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyCtrlG:
+			if h.doneFunc != nil {
+				h.doneFunc()
+			}
+
+			return nil
+
+		case tcell.KeyEnter:
+			if e := h.selectedEntry(); e != nil && h.loadFunc != nil {
+				h.loadFunc(e.Query)
+			}
+
+			return nil
+
+		case tcell.KeyCtrlR:
+			e := h.selectedEntry()
+			if e == nil {
+				return nil
+			}
+
+			if e.DryRun || e.Cancelled {
+				h.statusTextView.
+					SetText("no stored result for this query").
+					SetTextStyle(textStyleError)
+
+				return nil
+			}
+
+			h.renderEntry(e)
+
+			return nil
+
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			h.moveSelection(-1)
+
+			return nil
+
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			h.moveSelection(1)
+
+			return nil
+		}
+
+		return event
+	})
+}
+
+func (h *History) applyFilter(filter string) {
+	filter = strings.ToLower(filter)
+
+	h.filtered = h.filtered[:0]
+
+	for _, e := range h.entries {
+		if filter == "" || strings.Contains(strings.ToLower(e.Query), filter) {
+			h.filtered = append(h.filtered, e)
+		}
+	}
+
+	h.table.Clear()
+
+	for i, title := range []string{"end time", "bytes processed", "dry run", "query"} {
+		h.table.SetCell(0, i, tview.NewTableCell(title).SetStyle(textStyleDefault.Bold(true)).SetSelectable(false))
+	}
+
+	for i, e := range h.filtered {
+		dryRun := ""
+		if e.DryRun {
+			dryRun = "yes"
+		}
+
+		query := firstLine(e.Query)
+		if e.Cancelled {
+			query = "(cancelled) " + query
+		}
+
+		h.table.SetCell(i+1, 0, tview.NewTableCell(e.EndTime.Local().Format(historyTimeFormat)))
+		h.table.SetCell(i+1, 1, tview.NewTableCell(humanize.Bytes(uint64(e.TotalBytesProcessed))).SetAlign(tview.AlignRight))
+		h.table.SetCell(i+1, 2, tview.NewTableCell(dryRun))
+		h.table.SetCell(i+1, 3, tview.NewTableCell(tview.Escape(query)).SetExpansion(1))
+	}
+
+	h.table.Select(1, 0).ScrollToBeginning()
+	h.showSelectedQuery()
+}
+
+func (h *History) moveSelection(delta int) {
+	if len(h.filtered) == 0 {
+		return
+	}
+
+	row, _ := h.table.GetSelection()
+
+	row += delta
+	if row < 1 {
+		row = 1
+	}
+	if row > len(h.filtered) {
+		row = len(h.filtered)
+	}
+
+	h.table.Select(row, 0)
+}
+
+func (h *History) selectedEntry() *history.Entry {
+	row, _ := h.table.GetSelection()
+
+	if row < 1 || row > len(h.filtered) {
+		return nil
+	}
+
+	return h.filtered[row-1]
+}
+
+func (h *History) showSelectedQuery() {
+	e := h.selectedEntry()
+	if e == nil {
+		h.queryTextView.SetText("")
+		return
+	}
+
+	h.queryTextView.SetText(e.Query).ScrollToBeginning()
+}
+
+// renderEntry reads the whole result of the entry, which List leaves out, in
+// background and passes it to renderFunc. The result is discarded if history
+// is reloaded in the meantime.
+func (h *History) renderEntry(e *history.Entry) {
+	seq := h.reloadSeq
+
+	h.statusTextView.SetText("loading result...").SetTextStyle(textStyleDefault)
+
+	go func() {
+		r, err := h.history.Load(e.Key)
+
+		h.app.QueueUpdateDraw(func() {
+			if seq != h.reloadSeq {
+				return
+			}
+
+			if err != nil {
+				h.statusTextView.
+					SetText(fmt.Sprintf("cannot load result: %s", err)).
+					SetTextStyle(textStyleError)
+
+				return
+			}
+
+			h.statusTextView.SetText(historyHelp).SetTextStyle(textStyleDefault)
+
+			if h.renderFunc != nil {
+				h.renderFunc(r)
+			}
+		})
+	}()
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+
+	return s
+}
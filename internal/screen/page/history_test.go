@@ -0,0 +1,142 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/bigquery/bigquerytest"
+	"github.com/dtan4/bqc/internal/history"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddResult("select 'apple'", &bigquery.Result{Keys: []string{"f0_"}, Rows: [][]bigqueryapi.Value{{"apple"}}}).
+		AddResult("select 'banana'", &bigquery.Result{Keys: []string{"f0_"}, Rows: [][]bigqueryapi.Value{{"banana"}}})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 'apple'")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)")
+
+	// the result is shown slightly before the query is marked as finished
+	deadline := time.Now().Add(waitTimeout)
+	for qt.q.isQueryRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("the first query does not finish")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	qt.app.QueueUpdateDraw(func() {
+		qt.q.SetQuery("select 'banana'")
+	})
+	qt.ctrlX(tcell.KeyEnter, 0)
+
+	deadline = time.Now().Add(waitTimeout)
+	for len(fake.Queries()) < 2 || qt.q.isQueryRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("the second query is not run")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	qt.ctrlX(tcell.KeyRune, 'h')
+	qt.waitForText(t, "filter:", "select 'apple'", "select 'banana'", historyHelp)
+
+	qt.typeText("app")
+	qt.waitForTextGone(t, "select 'banana'")
+
+	// Ctrl-R shows the stored result without running the query
+	qt.sim.InjectKey(tcell.KeyCtrlR, 0, tcell.ModCtrl)
+	qt.waitForText(t, "[HISTORY] 1 row(s)", "apple")
+
+	if got := len(fake.Queries()); got != 2 {
+		t.Errorf("want no query run by showing history, got %d queries", got)
+	}
+
+	// Enter loads the query into the editor
+	qt.ctrlX(tcell.KeyRune, 'h')
+	qt.waitForText(t, "select 'banana'", historyHelp)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "filter:")
+
+	var text string
+
+	qt.app.QueueUpdate(func() {
+		text = qt.q.textArea.GetText()
+	})
+
+	if text != "select 'banana'" {
+		t.Errorf("want the newest query loaded, got: %q", text)
+	}
+}
+
+// failingStorage fails to read history entries.
+type failingStorage struct{}
+
+func (failingStorage) Close() error                    { return nil }
+func (failingStorage) Append(r *bigquery.Result) error { return nil }
+func (failingStorage) List() ([]*history.Entry, error) {
+	return nil, errors.New("database is locked")
+}
+func (failingStorage) Load(key string) (*bigquery.Result, error) {
+	return nil, errors.New("database is locked")
+}
+func (failingStorage) LastParameters(query string) ([]bigquery.Parameter, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestHistoryReload_error(t *testing.T) {
+	t.Parallel()
+
+	sim := tcell.NewSimulationScreen("UTF-8")
+
+	app := tview.NewApplication().SetScreen(sim)
+	sim.SetSize(testScreenWidth, testScreenHeight)
+
+	h := NewHistory(app, failingStorage{})
+	if err := h.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	runTestApp(t, app, h)
+
+	qt := &queryTest{app: app, sim: sim}
+
+	app.QueueUpdateDraw(h.Reload)
+	qt.waitForText(t, "cannot load history: database is locked")
+}
+
+func TestHistory_partialResult(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	// only the first page of rows is stored for results fetched page by page
+	if err := qt.history.Append(&bigquery.Result{
+		Query:     "select x from big",
+		Keys:      []string{"x"},
+		Rows:      [][]bigqueryapi.Value{{int64(1)}, {int64(2)}},
+		TotalRows: 5,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	qt.ctrlX(tcell.KeyRune, 'h')
+	qt.waitForText(t, "select x from big", historyHelp)
+
+	qt.sim.InjectKey(tcell.KeyCtrlR, 0, tcell.ModCtrl)
+	qt.waitForText(t, "[HISTORY] showing 2 of 5 row(s), the rest is not stored")
+}
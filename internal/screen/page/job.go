@@ -0,0 +1,124 @@
+package page
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	jobDetailsWidth  = 120
+	jobDetailsHeight = 30
+)
+
+// showJobDetails shows statistics and the query plan of the job which produced
+// the result of the current buffer.
+func (q *Query) showJobDetails() {
+	r := q.current.lastResult
+	if r == nil || r.JobID == "" {
+		q.statusTextView.SetText("no job details to show").SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.modalFunc == nil {
+		return
+	}
+
+	var closeModal func()
+
+	tv := tview.NewTextView().
+		SetText(formatJobDetails(r)).
+		SetWrap(false).
+		SetTextStyle(textStyleDefault)
+
+	tv.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape, event.Key() == tcell.KeyCtrlG, event.Key() == tcell.KeyRune && event.Rune() == 'q':
+			closeModal()
+
+			return nil
+		}
+
+		return event
+	})
+
+	tv.SetBorder(true).SetTitle("job details (Esc to close)")
+
+	closeModal = q.modalFunc(center(tv, jobDetailsWidth, jobDetailsHeight))
+}
+
+// jobStatus describes the billing of the job for the status bar.
+func jobStatus(r *bigquery.Result) string {
+	if r.JobID == "" {
+		return ""
+	}
+
+	if r.CacheHit {
+		return ", cache hit"
+	}
+
+	return fmt.Sprintf(", billed %s, %s of slot time", humanize.Bytes(uint64(r.TotalBytesBilled)), time.Duration(r.SlotMillis)*time.Millisecond)
+}
+
+// formatJobDetails describes the job statistics and the stages of its query
+// plan. The stage which ran the longest is marked, since it is usually what
+// to tune.
+func formatJobDetails(r *bigquery.Result) string {
+	var b strings.Builder
+
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "job ID\t%s\n", r.JobID)
+	fmt.Fprintf(w, "cache hit\t%t\n", r.CacheHit)
+	fmt.Fprintf(w, "bytes processed\t%s\n", humanize.Bytes(uint64(r.TotalBytesProcessed)))
+	fmt.Fprintf(w, "bytes billed\t%s\n", humanize.Bytes(uint64(r.TotalBytesBilled)))
+	fmt.Fprintf(w, "slot time\t%s\n", time.Duration(r.SlotMillis)*time.Millisecond)
+
+	w.Flush()
+
+	if len(r.Stages) == 0 {
+		b.WriteString("\nno query plan\n")
+
+		return b.String()
+	}
+
+	dominant := bigquery.DominantStage(r.Stages)
+
+	fmt.Fprintln(&b)
+
+	w = tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "\tSTAGE\tSTATUS\tDURATION\tINPUT ROWS\tOUTPUT ROWS\tSTEPS")
+
+	for i, s := range r.Stages {
+		mark := ""
+		if i == dominant {
+			mark = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			mark,
+			s.Name,
+			s.Status,
+			s.Duration().Round(time.Millisecond),
+			humanize.Comma(s.RecordsRead),
+			humanize.Comma(s.RecordsWritten),
+			strings.Join(s.Steps, ", "),
+		)
+	}
+
+	w.Flush()
+
+	if dominant >= 0 {
+		fmt.Fprintf(&b, "\n* %s dominates the plan, taking %s\n", r.Stages[dominant].Name, r.Stages[dominant].Duration().Round(time.Millisecond))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package page
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+func TestFormatJobDetails(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testcases := map[string]struct {
+		result *bigquery.Result
+		want   string
+	}{
+		"stages": {
+			result: &bigquery.Result{
+				JobID:               "job_abc",
+				TotalBytesProcessed: 2000,
+				TotalBytesBilled:    10000000,
+				SlotMillis:          1500,
+				Stages: []*bigquery.Stage{
+					{
+						Name:           "S00: Input",
+						Status:         "COMPLETE",
+						StartTime:      start,
+						EndTime:        start.Add(time.Second),
+						RecordsRead:    12345,
+						RecordsWritten: 10,
+						Steps:          []string{"READ", "WRITE"},
+					},
+					{
+						Name:           "S01: Output",
+						Status:         "COMPLETE",
+						StartTime:      start.Add(time.Second),
+						EndTime:        start.Add(4 * time.Second),
+						RecordsRead:    10,
+						RecordsWritten: 1,
+						Steps:          []string{"READ", "AGGREGATE", "WRITE"},
+					},
+				},
+			},
+			want: `job ID           job_abc
+cache hit        false
+bytes processed  2.0 kB
+bytes billed     10 MB
+slot time        1.5s
+
+   STAGE        STATUS    DURATION  INPUT ROWS  OUTPUT ROWS  STEPS
+   S00: Input   COMPLETE  1s        12,345      10           READ, WRITE
+*  S01: Output  COMPLETE  3s        10          1            READ, AGGREGATE, WRITE
+
+* S01: Output dominates the plan, taking 3s
+`,
+		},
+		"brackets are shown as is": {
+			result: &bigquery.Result{
+				JobID: "job_abc",
+				Stages: []*bigquery.Stage{
+					{
+						Name:   "S00: Input [retry]",
+						Status: "COMPLETE",
+						Steps:  []string{"READ"},
+					},
+				},
+			},
+			want: `job ID           job_abc
+cache hit        false
+bytes processed  0 B
+bytes billed     0 B
+slot time        0s
+
+  STAGE               STATUS    DURATION  INPUT ROWS  OUTPUT ROWS  STEPS
+  S00: Input [retry]  COMPLETE  0s        0           0            READ
+`,
+		},
+		"cache hit": {
+			result: &bigquery.Result{
+				JobID:    "job_abc",
+				CacheHit: true,
+			},
+			want: `job ID           job_abc
+cache hit        true
+bytes processed  0 B
+bytes billed     0 B
+slot time        0s
+
+no query plan
+`,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.want, formatJobDetails(tc.result)); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,10 +1,26 @@
 package page
 
-import "github.com/rivo/tview"
+import (
+	"context"
+
+	"github.com/rivo/tview"
+)
 
 type Page interface {
 	tview.Primitive
 
-	Init() error
+	Init(ctx context.Context) error
 	Close() error
 }
+
+// ModalFunc shows p on top of the current page and focuses it. The returned
+// function closes p.
+type ModalFunc func(p tview.Primitive) (close func())
+
+// center places p of the given size at the center of the screen.
+func center(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(p, 1, 1, 1, 1, 0, 0, true)
+}
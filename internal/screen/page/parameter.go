@@ -0,0 +1,94 @@
+package page
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	parameterFormWidth  = 64
+	parameterValueWidth = 40
+)
+
+// askParameters asks a value and a type of each query parameter, and calls run
+// with them unless the user cancels. The form is filled with the values last
+// given to the same query, which are looked up in history in background after
+// restarts.
+func (q *Query) askParameters(query string, names []string, run func(params []bigquery.Parameter)) {
+	if params, ok := q.parameters[query]; ok {
+		q.showParameterForm(query, names, params, run)
+		return
+	}
+
+	go func() {
+		params, err := q.history.LastParameters(query)
+
+		q.app.QueueUpdateDraw(func() {
+			// the form is just left empty on error
+			if err == nil && params != nil {
+				q.parameters[query] = params
+			}
+
+			q.showParameterForm(query, names, params, run)
+		})
+	}()
+}
+
+// showParameterForm shows the form filled with the given values.
+func (q *Query) showParameterForm(query string, names []string, values []bigquery.Parameter, run func(params []bigquery.Parameter)) {
+	last := map[string]bigquery.Parameter{}
+	for _, p := range values {
+		last[strings.ToLower(p.Name)] = p
+	}
+
+	var closeModal func()
+
+	cancel := func() {
+		closeModal()
+		q.statusTextView.SetText("[CANCELLED] query was not run").SetTextStyle(textStyleWarning)
+	}
+
+	form := tview.NewForm().SetItemPadding(0)
+
+	for _, name := range names {
+		p := last[strings.ToLower(name)]
+
+		typ := max(slices.Index(bigquery.ParameterTypes, p.Type), 0)
+
+		form.
+			AddInputField("@"+name, p.Value, parameterValueWidth, nil, nil).
+			AddDropDown("  type", bigquery.ParameterTypes, typ, nil)
+	}
+
+	form.
+		AddButton(modalButtonRun, func() {
+			params := make([]bigquery.Parameter, 0, len(names))
+
+			for i, name := range names {
+				_, typ := form.GetFormItem(2*i + 1).(*tview.DropDown).GetCurrentOption()
+
+				params = append(params, bigquery.Parameter{
+					Name:  name,
+					Type:  typ,
+					Value: form.GetFormItem(2 * i).(*tview.InputField).GetText(),
+				})
+			}
+
+			closeModal()
+
+			q.parameters[query] = params
+			run(params)
+		}).
+		AddButton(modalButtonCancel, cancel).
+		SetCancelFunc(cancel)
+
+	form.SetBorder(true).SetTitle(fmt.Sprintf("query parameters (%d)", len(names)))
+
+	// 2 rows for each parameter, and the buttons with borders and paddings
+	closeModal = q.modalFunc(center(form, parameterFormWidth, 2*len(names)+6))
+}
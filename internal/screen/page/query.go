@@ -2,7 +2,11 @@ package page
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/atotto/clipboard"
@@ -11,39 +15,132 @@ import (
 	"github.com/rivo/tview"
 
 	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/catalog"
 	"github.com/dtan4/bqc/internal/checkpoint"
+	"github.com/dtan4/bqc/internal/googlesql"
 	"github.com/dtan4/bqc/internal/history"
 	"github.com/dtan4/bqc/internal/renderer"
 )
 
-type Query struct {
-	*tview.Grid
+const (
+	// resultPageSize is the number of rows fetched at once
+	resultPageSize = 1000
+)
 
-	app *tview.Application
+// QueryOptions configures Query page.
+type QueryOptions struct {
+	// MaxRows is the maximum number of rows kept in memory. 0 means no limit.
+	MaxRows int
+	// ConfirmBytes is the estimated bytes processed above which the user is
+	// asked before running a query. 0 disables the confirmation.
+	ConfirmBytes int64
+	// MaxBytesBilled is the limit of bytes billed set to queries. Queries
+	// estimated above it are not offered to run, since BigQuery refuses them.
+	// 0 means no limit.
+	MaxBytesBilled int64
+	// PricePerTiB is the on-demand price per TiB used to estimate query cost.
+	PricePerTiB float64
+	// LiveDryRunDelay is how long after typing stops the statement at the
+	// cursor is dry-run to show its cost or error. 0 disables live dry runs.
+	LiveDryRunDelay time.Duration
+}
 
-	bqClient         *bigquery.Client
-	defaultRenderer  renderer.Renderer
-	markdownRenderer *renderer.MarkdownRenderer
-	tsvRenderer      *renderer.TSVRenderer
-	checkpoint       *checkpoint.Checkpoint
-	history          history.Storage
+type Query struct {
+	*tview.Grid
 
-	textArea          *tview.TextArea
+	app  *tview.Application
+	ctx  context.Context
+	opts QueryOptions
+
+	bqClient          bigquery.QueryBackend
+	defaultRenderer   renderer.Renderer
+	markdownRenderer  *renderer.MarkdownRenderer
+	tsvRenderer       *renderer.TSVRenderer
+	jsonRenderer      *renderer.JSONRenderer
+	jsonLinesRenderer *renderer.JSONLinesRenderer
+	checkpoints       *checkpoint.Dir
+	history           history.Storage
+
+	tabsTextView      *tview.TextView
+	textArea          *editor
 	borderTextView    *tview.TextView
+	resultFlex        *tview.Flex
+	resultPages       *tview.Pages
 	resultTextView    *tview.TextView
+	resultTable       *resultTable
+	schemaTextView    *tview.TextView
 	statusTextView    *tview.TextView
 	ctrlXTextView     *tview.TextView
 	cursorPosTextView *tview.TextView
+	// sessionTextView shows the session ID and age. It is laid out only in
+	// session mode.
+	sessionTextView *tview.TextView
 
 	ctrlXMode  bool
-	lastResult *bigquery.Result
+	showSchema bool
+
+	// catalog provides names for completion. nil if completion of datasets,
+	// tables and columns is disabled.
+	catalog *catalog.Cache
+	// completion is the popup shown while typing. nil if hidden.
+	completion *completionPopup
+	// completionTrigger is true while a key which updates the popup is handled
+	completionTrigger bool
+	// completionAt is the offset where the completion is requested last
+	completionAt int
+
+	// buffers are shown as tabs in this order. buffers and current are
+	// accessed only in the application's main loop.
+	buffers []*buffer
+	current *buffer
+
+	// cancelQuery cancels the running query. nil if no query is running.
+	cancelQuery context.CancelFunc
+	// queryBuffer is the buffer the result of the running query is kept in.
+	// nil if the running query doesn't change any buffer, e.g. saving a
+	// result.
+	queryBuffer *buffer
+	mu          sync.Mutex
+
+	// liveDryRunSeq identifies the latest text change, so that results of
+	// dry runs for older text are discarded
+	liveDryRunSeq    int
+	liveDryRunTimer  *time.Timer
+	cancelLiveDryRun context.CancelFunc
+
+	// parameters are the parameter values last given to each query text
+	parameters map[string][]bigquery.Parameter
+
+	// lastDestination is where a result is last saved into, which is filled
+	// in the next time
+	lastDestination *bigquery.Destination
+
+	// stopSessionTicker stops refreshing sessionTextView. nil if not in
+	// session mode.
+	stopSessionTicker func()
+
+	openHistoryFunc func()
+	openCatalogFunc func()
+	modalFunc       ModalFunc
 }
 
 var _ Page = (*Query)(nil)
 
+const (
+	resultPageMessage = "message"
+	resultPageTable   = "table"
+
+	modalButtonRun    = "Run"
+	modalButtonCancel = "Cancel"
+
+	messageAnotherQueryRunning = "another query is running, press Ctrl-X K to cancel it"
+)
+
 // NewQuery creates Query page.
 //
 // +-------------------------------------------------------------------+
+// | tabsTextView (height: 1)                                          |
+// +-------------------------------------------------------------------+
 // | textArea                                                          |
 // |                                                                   |
 // |                                                                   |
@@ -52,58 +149,94 @@ var _ Page = (*Query)(nil)
 // +-------------------------------------------------------------------+
 // | borderTextView (height: 1)                                        |
 // +-------------------------------------------------------------------+
-// | resultTextView                                                    |
-// |                                                                   |
-// |                                                                   |
-// |                                                                   |
-// |                                                                   |
+// | resultPages (resultTextView for messages,  | schemaTextView       |
+// | resultTable for rows)                      | (width: 48, toggled) |
+// |                                            |                      |
+// |                                            |                      |
+// |                                            |                      |
 // +-------------------------------------------------------------------+
 // | statusTextView                | ctrlXTextView | cursorPosTextView |
 // |                               | (width: 8)    | (width: 18)       |
 // +-------------------------------------------------------------------+
+//
+// In session mode, sessionTextView (width: 32) is placed between
+// statusTextView and ctrlXTextView.
 func NewQuery(
 	app *tview.Application,
-	bqClient *bigquery.Client,
-	checkpoint *checkpoint.Checkpoint,
+	bqClient bigquery.QueryBackend,
+	checkpoints *checkpoint.Dir,
 	history history.Storage,
+	opts QueryOptions,
 ) *Query {
 	q := &Query{
 		Grid: tview.NewGrid(),
 
-		app: app,
-
-		bqClient:         bqClient,
-		defaultRenderer:  &renderer.TableRenderer{},
-		markdownRenderer: &renderer.MarkdownRenderer{},
-		tsvRenderer:      &renderer.TSVRenderer{},
-		checkpoint:       checkpoint,
-		history:          history,
-
-		textArea:          tview.NewTextArea(),
+		app:  app,
+		ctx:  context.Background(),
+		opts: opts,
+
+		bqClient:          bqClient,
+		defaultRenderer:   &renderer.TableRenderer{},
+		markdownRenderer:  &renderer.MarkdownRenderer{},
+		tsvRenderer:       &renderer.TSVRenderer{},
+		jsonRenderer:      &renderer.JSONRenderer{},
+		jsonLinesRenderer: &renderer.JSONLinesRenderer{},
+		checkpoints:       checkpoints,
+		history:           history,
+
+		tabsTextView:      tview.NewTextView(),
+		textArea:          newEditor(),
 		borderTextView:    tview.NewTextView(),
+		resultFlex:        tview.NewFlex(),
+		resultPages:       tview.NewPages(),
 		resultTextView:    tview.NewTextView(),
+		resultTable:       newResultTable(),
+		schemaTextView:    tview.NewTextView(),
 		statusTextView:    tview.NewTextView(),
 		ctrlXTextView:     tview.NewTextView(),
 		cursorPosTextView: tview.NewTextView(),
+		sessionTextView:   tview.NewTextView(),
+
+		ctrlXMode: false,
 
-		ctrlXMode:  false,
-		lastResult: nil,
+		parameters: map[string][]bigquery.Parameter{},
 	}
 
-	q.SetRows(0, 1, 0, 1)
-	q.SetColumns(0, 8, 18)
+	columns := []int{0, 8, 18}
+	if q.sessionBackend() != nil {
+		columns = []int{0, sessionStatusWidth, 8, 18}
+	}
+
+	// the editor and the result span all columns of the status bar
+	span := len(columns)
+
+	q.SetRows(1, 0, 1, 0, 1)
+	q.SetColumns(columns...)
 
-	q.AddItem(q.textArea, 0, 0, 1, 1, 0, 0, true)
-	q.AddItem(q.borderTextView, 1, 0, 1, 1, 0, 0, false)
-	q.AddItem(q.resultTextView, 2, 0, 1, 1, 0, 0, false)
-	q.AddItem(q.statusTextView, 3, 0, 1, 1, 0, 0, false)
-	q.AddItem(q.ctrlXTextView, 3, 1, 1, 1, 0, 0, false)
-	q.AddItem(q.cursorPosTextView, 3, 2, 1, 1, 0, 0, false)
+	q.AddItem(q.tabsTextView, 0, 0, 1, span, 0, 0, false)
+	q.AddItem(q.textArea, 1, 0, 1, span, 0, 0, true)
+	q.AddItem(q.borderTextView, 2, 0, 1, span, 0, 0, false)
+	q.AddItem(q.resultFlex, 3, 0, 1, span, 0, 0, false)
+	q.AddItem(q.statusTextView, 4, 0, 1, 1, 0, 0, false)
+
+	if span == 4 {
+		q.AddItem(q.sessionTextView, 4, 1, 1, 1, 0, 0, false)
+	}
+
+	q.AddItem(q.ctrlXTextView, 4, span-2, 1, 1, 0, 0, false)
+	q.AddItem(q.cursorPosTextView, 4, span-1, 1, 1, 0, 0, false)
 
 	return q
 }
 
-func (q *Query) Init() error {
+func (q *Query) Init(ctx context.Context) error {
+	q.ctx = ctx
+
+	q.tabsTextView.
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(false)
+
 	q.textArea.SetTextStyle(textStyleDefault).SetWordWrap(false)
 
 	q.borderTextView.SetText("--- result ---")
@@ -112,6 +245,15 @@ func (q *Query) Init() error {
 		q.app.Draw()
 	})
 
+	q.resultPages.
+		AddPage(resultPageMessage, q.resultTextView, true, true).
+		AddPage(resultPageTable, q.resultTable, true, false)
+
+	q.resultFlex.AddItem(q.resultPages, 0, 1, false)
+
+	q.schemaTextView.SetTextStyle(textStyleDefault).SetWordWrap(false).SetText(formatSchema(nil))
+	q.schemaTextView.SetBorder(true).SetTitle("schema")
+
 	q.statusTextView.SetTextStyle(textStyleDefault).SetChangedFunc(func() {
 		q.app.Draw()
 	})
@@ -124,26 +266,137 @@ func (q *Query) Init() error {
 		q.app.Draw()
 	})
 
+	q.textArea.SetChangedFunc(func() {
+		q.onTextChanged()
+		q.scheduleLiveDryRun()
+	})
+
 	q.textArea.SetMovedFunc(func() {
 		row, col, _, _ := q.textArea.GetCursor()
 		// row and col starts from 0
 		q.cursorPosTextView.SetText(fmt.Sprintf("(Ln %d, Col %d)", row+1, col+1))
 	})
 
-	query, err := q.checkpoint.Load()
-	if err != nil {
-		// ignore error
-		query = ""
+	if q.sessionBackend() != nil {
+		q.sessionTextView.SetTextStyle(textStyleDefault).SetChangedFunc(func() {
+			q.app.Draw()
+		})
+
+		q.updateSessionStatus()
+		q.stopSessionTicker = q.startSessionTicker()
 	}
-	q.textArea.SetText(query, false)
+
+	q.loadBuffers()
 
 	q.bindKeys()
+	q.bindResultTableKeys()
 
 	return nil
 }
 
+// SetOpenHistoryFunc sets the handler called when the user opens query history.
+func (q *Query) SetOpenHistoryFunc(handler func()) *Query {
+	q.openHistoryFunc = handler
+	return q
+}
+
+// SetOpenCatalogFunc sets the handler called when the user opens the dataset
+// and table browser.
+func (q *Query) SetOpenCatalogFunc(handler func()) *Query {
+	q.openCatalogFunc = handler
+	return q
+}
+
+// SetCatalog sets the catalog used to complete datasets, tables and columns.
+func (q *Query) SetCatalog(c *catalog.Cache) *Query {
+	q.catalog = c
+	return q
+}
+
+// SetModalFunc sets the function to show dialogs.
+func (q *Query) SetModalFunc(f ModalFunc) *Query {
+	q.modalFunc = f
+	return q
+}
+
+// SetQuery replaces the editor content of the current buffer with the given
+// query.
+func (q *Query) SetQuery(query string) {
+	q.textArea.SetText(query, false)
+}
+
+// InsertText replaces the selected text of the current buffer with the given
+// text, or inserts it at the cursor if nothing is selected.
+func (q *Query) InsertText(text string) {
+	_, start, end := q.textArea.GetSelection()
+	q.textArea.Replace(start, end, text)
+}
+
+// ShowResult renders the given result, e.g. loaded from history, without
+// running the query again.
+func (q *Query) ShowResult(r *bigquery.Result) {
+	b := q.current
+
+	q.closeStream(b)
+	b.setResult(r)
+
+	q.showResultTable(b, r)
+
+	q.statusTextView.
+		SetText(
+			fmt.Sprintf(
+				"[HISTORY] %s, finished at %s, processed %s of data%s",
+				historyRowsStatus(r),
+				r.EndTime.Local().Format(historyTimeFormat),
+				humanize.Bytes(uint64(r.TotalBytesProcessed)),
+				scriptStatus(r),
+			),
+		).
+		SetTextStyle(textStyleDefault)
+}
+
+// historyRowsStatus tells how many rows of the result are stored. Only the
+// first page is stored for results fetched page by page.
+func historyRowsStatus(r *bigquery.Result) string {
+	if int64(len(r.Rows)) < r.TotalRows {
+		return fmt.Sprintf("showing %d of %d row(s), the rest is not stored", len(r.Rows), r.TotalRows)
+	}
+
+	return fmt.Sprintf("%d row(s)", len(r.Rows))
+}
+
+// ShowPreview renders rows of the table read without running a query.
+func (q *Query) ShowPreview(name string, r *bigquery.Result) {
+	b := q.current
+
+	q.closeStream(b)
+	b.setResult(r)
+
+	q.showResultTable(b, r)
+
+	q.statusTextView.
+		SetText(fmt.Sprintf("[PREVIEW] %s of %s, no bytes billed", q.rowsStatus(r), name)).
+		SetTextStyle(textStyleDefault)
+}
+
 func (q *Query) Close() error {
-	if err := q.checkpoint.Save(q.textArea.GetText(), time.Now()); err != nil {
+	for _, b := range q.buffers {
+		q.closeStream(b)
+	}
+
+	if q.liveDryRunTimer != nil {
+		q.liveDryRunTimer.Stop()
+	}
+
+	if q.cancelLiveDryRun != nil {
+		q.cancelLiveDryRun()
+	}
+
+	if q.stopSessionTicker != nil {
+		q.stopSessionTicker()
+	}
+
+	if err := q.saveBuffers(); err != nil {
 		return fmt.Errorf("save checkpoint: %w", err)
 	}
 
@@ -151,9 +404,6 @@ func (q *Query) Close() error {
 }
 
 func (q *Query) bindKeys() {
-	// TODO: Is there a better way to propagate *global* context?
-	ctx := context.Background()
-
 	q.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if q.ctrlXMode {
 			q.ctrlXMode = false
@@ -165,22 +415,83 @@ func (q *Query) bindKeys() {
 
 			case tcell.KeyEnter:
 				query := q.textArea.GetText()
-				q.runQuery(ctx, query, false)
+				q.runQuery(q.ctx, query, 0, false)
 
 			case tcell.KeyRune:
 				switch event.Rune() {
+				case 'a':
+					q.confirmEndSession()
+
+				case 'b':
+					if q.openCatalogFunc != nil {
+						q.openCatalogFunc()
+					}
+
 				case 'c':
-					q.copyResultToClipboard()
+					q.copyResultToClipboardAs(q.defaultRenderer, "table")
 
 				case 'd':
 					query := q.textArea.GetText()
-					q.runQuery(ctx, query, true)
+					q.runQuery(q.ctx, query, 0, true)
+
+				case 'e':
+					q.runStatementAtCursor()
+
+				case 'h':
+					if q.openHistoryFunc != nil {
+						q.openHistoryFunc()
+					}
+
+				case 'i':
+					q.showJobDetails()
+
+				case 'j':
+					q.copyResultToClipboardAs(q.jsonRenderer, "JSON")
+
+				case 'k':
+					q.cancelRunningQuery()
+
+				case 'l':
+					q.copyResultToClipboardAs(q.jsonLinesRenderer, "JSON Lines")
 
 				case 'm':
-					q.copyResultToClipboardAsMarkdown()
+					q.copyResultToClipboardAs(q.markdownRenderer, "Markdown table")
+
+				case 'n':
+					q.openNewBuffer()
+
+				case 'o':
+					q.toggleFocus()
+
+				case 'p':
+					q.askDestination()
+
+				case 'r':
+					q.runSelection()
+
+				case 's':
+					q.toggleSchema()
 
 				case 't':
-					q.copyResultToClipboardAsTSV()
+					q.copyResultToClipboardAs(q.tsvRenderer, "TSV")
+
+				case 'v':
+					q.selectStatementResult()
+
+				case 'w':
+					q.confirmCloseBuffer()
+
+				case ',':
+					q.confirmRenameBuffer()
+
+				case '[':
+					q.moveBuffer(-1)
+
+				case ']':
+					q.moveBuffer(1)
+
+				case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+					q.switchBufferAt(int(event.Rune() - '1'))
 				}
 			default:
 				// do nothing
@@ -188,6 +499,12 @@ func (q *Query) bindKeys() {
 
 			return nil
 		} else {
+			if q.textArea.HasFocus() {
+				if event = q.handleCompletionKey(event); event == nil {
+					return nil
+				}
+			}
+
 			switch event.Key() {
 			case tcell.KeyCtrlUnderscore:
 				return tcell.NewEventKey(tcell.KeyCtrlZ, 0, tcell.ModNone)
@@ -221,155 +538,614 @@ func (q *Query) bindKeys() {
 	})
 }
 
-func (q *Query) runQuery(ctx context.Context, query string, dryRun bool) {
+func (q *Query) bindResultTableKeys() {
+	q.resultTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			q.app.SetFocus(q.textArea)
+
+			return nil
+
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 's':
+				q.resultTable.SortBySelectedColumn()
+
+				return nil
+
+			case 'c':
+				q.copyResultSelectionToClipboard("cell", q.resultTable.SelectedCell)
+
+				return nil
+
+			case 'r':
+				q.copyResultSelectionToClipboard("row", q.resultTable.SelectedRow)
+
+				return nil
+
+			case 'C':
+				q.copyResultSelectionToClipboard("column", q.resultTable.SelectedColumn)
+
+				return nil
+
+			case 'm':
+				q.fetchMoreRows(false)
+
+				return nil
+			}
+		}
+
+		return event
+	})
+
+	q.resultTable.SetSelectionChangedFunc(func(row, column int) {
+		// fetch the next page when the user moves the cursor to the last loaded
+		// row
+		if q.resultTable.HasFocus() && row >= q.resultTable.GetRowCount()-1 {
+			q.fetchMoreRows(true)
+		}
+	})
+}
+
+// runStatementAtCursor runs the statement containing the cursor.
+func (q *Query) runStatementAtCursor() {
+	text := q.textArea.GetText()
+	_, cursor, _ := q.textArea.GetSelection()
+
+	stmt, ok := googlesql.StatementAt(text, cursor)
+	if !ok {
+		q.statusTextView.SetText("no statement at the cursor").SetTextStyle(textStyleError)
+		return
+	}
+
+	q.runQuery(q.ctx, stmt.Text, stmt.Start, false)
+}
+
+// runSelection runs the selected text.
+func (q *Query) runSelection() {
+	selected, start, _ := q.textArea.GetSelection()
+
+	if strings.TrimSpace(selected) == "" {
+		q.statusTextView.SetText("no text is selected").SetTextStyle(textStyleError)
+		return
+	}
+
+	q.runQuery(q.ctx, selected, start, false)
+}
+
+// runQuery runs the query in background, asking values of its parameters
+// first if any. offset is the byte offset of query in the editor, used to
+// locate errors.
+func (q *Query) runQuery(ctx context.Context, query string, offset int, dryRun bool) {
+	names := googlesql.Parameters(query)
+	if len(names) == 0 || q.modalFunc == nil {
+		q.startQuery(ctx, query, offset, dryRun, nil)
+		return
+	}
+
+	if q.isQueryRunning() {
+		q.statusTextView.SetText(messageAnotherQueryRunning).SetTextStyle(textStyleError)
+		return
+	}
+
+	q.askParameters(query, names, func(params []bigquery.Parameter) {
+		q.startQuery(ctx, query, offset, dryRun, params)
+	})
+}
+
+// startQuery runs the query with the given parameters in background. The
+// result is kept in the current buffer even if the user switches to another
+// one while running.
+func (q *Query) startQuery(ctx context.Context, query string, offset int, dryRun bool, params []bigquery.Parameter) {
+	b := q.current
+
 	msgPrefix := ""
 	if dryRun {
 		msgPrefix = "[dry-run] "
 	}
 
+	q.mu.Lock()
+	if q.cancelQuery != nil {
+		q.mu.Unlock()
+		q.statusTextView.SetText(messageAnotherQueryRunning).SetTextStyle(textStyleError)
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	q.cancelQuery = cancel
+	q.queryBuffer = b
+	q.mu.Unlock()
+
+	if !dryRun {
+		q.closeStream(b)
+	}
+
 	q.statusTextView.
 		SetText(fmt.Sprintf("%srunning query...", msgPrefix)).
 		SetTextStyle(textStyleDefault)
 
-	elapsedSecond := 1
+	go func() {
+		// ctx is kept alive on success to fetch the rest of rows
+		keepCtx := false
+
+		defer func() {
+			q.mu.Lock()
+			q.cancelQuery = nil
+			q.queryBuffer = nil
+			q.mu.Unlock()
+
+			if !keepCtx {
+				cancel()
+			}
+		}()
+
+		start := time.Now()
+
+		if !dryRun && q.opts.ConfirmBytes > 0 {
+			ok, err := q.confirmCost(ctx, query, params)
+			if err != nil {
+				q.handleQueryError(b, query, offset, dryRun, start, err)
+
+				return
+			}
+
+			if !ok {
+				q.setStatusAsync("[CANCELLED] query was not run", textStyleWarning)
+
+				return
+			}
+
+			start = time.Now()
+		}
+
+		stopTicker := q.startElapsedTicker(msgPrefix)
+
+		if dryRun {
+			r, err := q.bqClient.DryRunQuery(ctx, query, params...)
+			if err != nil {
+				stopTicker()
+				q.handleQueryError(b, query, offset, dryRun, start, err)
+
+				return
+			}
+
+			msg := fmt.Sprintf("This query will process %s of data.", humanize.Bytes(uint64(r.TotalBytesProcessed)))
+
+			historyErr := q.history.Append(r)
+
+			stopTicker()
+
+			q.app.QueueUpdateDraw(func() {
+				q.showResultMessage(b, msg)
+				q.setStatusWithHistoryError(
+					fmt.Sprintf("[SUCCESS] %stook %.2f seconds", msgPrefix, time.Since(start).Seconds()),
+					textStyleSuceess,
+					historyErr,
+				)
+			})
+		} else {
+			r, stream, err := q.bqClient.StreamQuery(ctx, query, q.firstPageSize(), params...)
+			if err != nil {
+				stopTicker()
+				q.handleQueryError(b, query, offset, dryRun, start, err)
+
+				return
+			}
+
+			historyErr := q.history.Append(r)
+
+			stopTicker()
+
+			keepCtx = true
+
+			q.app.QueueUpdateDraw(func() {
+				// the buffer may have been closed before the result arrived
+				if !slices.Contains(q.buffers, b) {
+					cancel()
+					return
+				}
 
+				b.setResult(r)
+				b.stream = stream
+				b.cancelStream = cancel
+				q.showResultTable(b, r)
+				q.setStatusWithHistoryError(
+					fmt.Sprintf(
+						"[SUCCESS] %s%s, took %.2f seconds, processed %s of data%s%s",
+						msgPrefix,
+						q.rowsStatus(r),
+						time.Since(start).Seconds(),
+						humanize.Bytes(uint64(r.TotalBytesProcessed)),
+						jobStatus(r),
+						scriptStatus(r),
+					),
+					textStyleSuceess,
+					historyErr,
+				)
+			})
+		}
+	}()
+}
+
+// startElapsedTicker shows the elapsed time of the running query every second
+// until the returned function is called.
+func (q *Query) startElapsedTicker(msgPrefix string) (stop func()) {
 	ticker := time.NewTicker(1 * time.Second)
 	done := make(chan bool)
 
 	go func() {
+		defer ticker.Stop()
+
+		elapsedSecond := 1
+
 		for {
 			select {
 			case <-done:
 				return
 			case <-ticker.C:
-				q.statusTextView.
-					SetText(fmt.Sprintf("%srunning query (%ds)...", msgPrefix, elapsedSecond)).
-					SetTextStyle(textStyleDefault)
+				q.setStatusAsync(fmt.Sprintf("%srunning query (%ds)...", msgPrefix, elapsedSecond), textStyleDefault)
 				elapsedSecond += 1
 			}
 		}
 	}()
 
-	go func() {
-		start := time.Now()
+	// done is unbuffered so that no status update from the ticker is left
+	// after stop returns
+	return func() {
+		done <- true
+	}
+}
 
-		result := ""
+// confirmCost estimates the bytes processed by the query with a dry run, and
+// asks the user whether to run it if the estimate exceeds the threshold. A
+// query estimated above MaxBytesBilled is only told to be refused. This must be
+// called outside of the application's main loop.
+func (q *Query) confirmCost(ctx context.Context, query string, params []bigquery.Parameter) (bool, error) {
+	q.setStatusAsync("estimating query cost...", textStyleDefault)
 
-		if dryRun {
-			r, err := q.bqClient.DryRunQuery(ctx, query)
-			if err != nil {
-				done <- true
-				q.resultTextView.SetText(err.Error())
-				q.statusTextView.
-					SetText(fmt.Sprintf("[ERROR] %scannot run query", msgPrefix)).
-					SetTextStyle(textStyleError)
+	r, err := q.bqClient.DryRunQuery(ctx, query, params...)
+	if err != nil {
+		return false, err
+	}
 
-				return
+	overLimit := q.opts.MaxBytesBilled > 0 && r.TotalBytesProcessed > q.opts.MaxBytesBilled
+
+	if !overLimit && r.TotalBytesProcessed <= q.opts.ConfirmBytes || q.modalFunc == nil {
+		return true, nil
+	}
+
+	text := fmt.Sprintf(
+		"This query will process %s of data (about $%.2f).\nRun it anyway?",
+		humanize.Bytes(uint64(r.TotalBytesProcessed)),
+		estimateCost(r.TotalBytesProcessed, q.opts.PricePerTiB),
+	)
+	buttons := []string{modalButtonRun, modalButtonCancel}
+
+	if overLimit {
+		text = fmt.Sprintf(
+			"This query will process %s of data (about $%.2f), over the limit of %s.\nBigQuery would refuse it. Raise --max-bytes-billed to run it.",
+			humanize.Bytes(uint64(r.TotalBytesProcessed)),
+			estimateCost(r.TotalBytesProcessed, q.opts.PricePerTiB),
+			humanize.Bytes(uint64(q.opts.MaxBytesBilled)),
+		)
+		buttons = []string{modalButtonCancel}
+	}
+
+	answer := make(chan bool, 1)
+
+	var closeModal func()
+
+	q.app.QueueUpdateDraw(func() {
+		modal := tview.NewModal().
+			SetText(text).
+			AddButtons(buttons).
+			SetDoneFunc(func(_ int, label string) {
+				closeModal()
+				answer <- label == modalButtonRun
+			})
+
+		closeModal = q.modalFunc(modal)
+		q.statusTextView.SetText("waiting for confirmation...").SetTextStyle(textStyleWarning)
+	})
+
+	select {
+	case ok := <-answer:
+		return ok, nil
+	case <-ctx.Done():
+		// closeModal is set in the main loop, where this runs after the
+		// modal is shown
+		q.app.QueueUpdateDraw(func() {
+			if closeModal != nil {
+				closeModal()
 			}
+		})
 
-			result = fmt.Sprintf("This query will process %s of data.", humanize.Bytes(uint64(r.TotalBytesProcessed)))
+		return false, ctx.Err()
+	}
+}
 
-			if err := q.history.Append(r); err != nil {
-				done <- true
-				q.statusTextView.
-					SetText(err.Error()).
-					SetTextStyle(textStyleError)
+// estimateCost returns the on-demand price of processing the given bytes.
+func estimateCost(bytes int64, pricePerTiB float64) float64 {
+	return float64(bytes) / (1 << 40) * pricePerTiB
+}
 
-				return
+// handleQueryError shows the error of a failed query. A query cancelled by the
+// user is recorded in history instead. offset is the byte offset of query in the
+// editor. This must be called outside of the application's main loop.
+func (q *Query) handleQueryError(b *buffer, query string, offset int, dryRun bool, start time.Time, err error) {
+	msgPrefix := ""
+	if dryRun {
+		msgPrefix = "[dry-run] "
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		q.app.QueueUpdateDraw(func() {
+			msg := err.Error()
+			status := fmt.Sprintf("[ERROR] %scannot run query", msgPrefix)
+
+			var qe *bigquery.QueryError
+			if errors.As(err, &qe) {
+				msg = qe.Message
+				if qe.Reason != "" {
+					msg += fmt.Sprintf(" (%s)", qe.Reason)
+				}
+
+				if qe.HasPosition() {
+					line, column := qe.Line, qe.Column
+
+					// the position is meaningless once the query is edited
+					if text := q.textArea.GetText(); b == q.current && offset <= len(text) && strings.HasPrefix(text[offset:], query) {
+						q.textArea.ShowError(offset, qe.Line, qe.Column)
+						q.app.SetFocus(q.textArea)
+
+						// report the position in the editor rather than in the statement
+						row, col, _, _ := q.textArea.GetCursor()
+						line, column = row+1, col+1
+					}
+
+					status += fmt.Sprintf(" at line %d, column %d", line, column)
+				}
 			}
 
-			done <- true
+			q.showResultMessage(b, msg)
+			q.statusTextView.SetText(status).SetTextStyle(textStyleError)
+		})
 
-			q.statusTextView.
-				SetText(fmt.Sprintf("[SUCCESS] %stook %.2f seconds", msgPrefix, time.Since(start).Seconds())).
-				SetTextStyle(textStyleSuceess)
-		} else {
-			r, err := q.bqClient.RunQuery(ctx, query)
-			if err != nil {
-				done <- true
-				q.resultTextView.SetText(err.Error())
+		return
+	}
+
+	r := &bigquery.Result{
+		Query:     query,
+		DryRun:    dryRun,
+		Cancelled: true,
+		EndTime:   time.Now(),
+	}
+
+	historyErr := q.history.Append(r)
+
+	q.app.QueueUpdateDraw(func() {
+		q.showResultMessage(b, "")
+		q.setStatusWithHistoryError(
+			fmt.Sprintf("[CANCELLED] %scancelled after %.2f seconds", msgPrefix, time.Since(start).Seconds()),
+			textStyleWarning,
+			historyErr,
+		)
+	})
+}
+
+// setStatusWithHistoryError updates the status bar with msg. A failure to
+// record the query in history doesn't hide the result, but is appended to msg
+// as a warning. This must be called in the application's main loop.
+func (q *Query) setStatusWithHistoryError(msg string, style tcell.Style, historyErr error) {
+	if historyErr != nil {
+		msg += fmt.Sprintf(" [WARNING] not saved in history: %s", historyErr)
+		style = textStyleWarning
+	}
+
+	q.statusTextView.SetText(msg).SetTextStyle(style)
+}
+
+// setStatusAsync updates the status bar. This must be called outside of the
+// application's main loop.
+func (q *Query) setStatusAsync(msg string, style tcell.Style) {
+	q.app.QueueUpdateDraw(func() {
+		q.statusTextView.SetText(msg).SetTextStyle(style)
+	})
+}
+
+// firstPageSize returns the number of rows fetched with the query.
+func (q *Query) firstPageSize() int {
+	if q.opts.MaxRows > 0 && q.opts.MaxRows < resultPageSize {
+		return q.opts.MaxRows
+	}
+
+	return resultPageSize
+}
+
+// fetchMoreRows fetches the next page of the result in the current buffer in
+// background. If auto is true, nothing is reported when there are no more rows
+// to fetch.
+func (q *Query) fetchMoreRows(auto bool) {
+	b := q.current
+
+	if b.fetching {
+		return
+	}
+
+	// the stream belongs to the result of the whole script
+	if b.stream == nil || b.stream.Done() || b.lastResult == nil || b.script != nil && b.lastResult != b.script {
+		if !auto {
+			q.statusTextView.SetText("all rows are loaded").SetTextStyle(textStyleDefault)
+		}
+
+		return
+	}
+
+	n := resultPageSize
+
+	if q.opts.MaxRows > 0 {
+		n = min(n, q.opts.MaxRows-len(b.lastResult.Rows))
+
+		if n <= 0 {
+			if !auto {
 				q.statusTextView.
-					SetText(fmt.Sprintf("[ERROR] %scannot run query", msgPrefix)).
-					SetTextStyle(textStyleError)
+					SetText(fmt.Sprintf("%s, reached the limit of %d rows", q.rowsStatus(b.lastResult), q.opts.MaxRows)).
+					SetTextStyle(textStyleWarning)
+			}
 
+			return
+		}
+	}
+
+	b.fetching = true
+	q.statusTextView.SetText("fetching more rows...").SetTextStyle(textStyleDefault)
+
+	r := b.lastResult
+	stream := b.stream
+
+	go func() {
+		rows, err := stream.Next(n)
+
+		q.app.QueueUpdateDraw(func() {
+			b.fetching = false
+
+			if b.lastResult != r {
+				// another result is shown while fetching
 				return
 			}
 
-			result, err = q.defaultRenderer.Render(r)
-			if err != nil {
-				done <- true
-				q.statusTextView.
-					SetText(fmt.Sprintf("[ERROR] %scannot render result", msgPrefix)).
-					SetTextStyle(textStyleError)
+			r.Rows = append(r.Rows, rows...)
 
+			if errors.Is(err, context.Canceled) {
+				// the stream is closed by another query
+				err = nil
+			}
+
+			if q.current != b {
+				// the rows and the error are shown when the user switches back
+				b.fetchErr = err
 				return
 			}
 
-			if err := q.history.Append(r); err != nil {
-				done <- true
+			q.resultTable.AppendRows(rows)
+
+			if err != nil {
 				q.statusTextView.
-					SetText(err.Error()).
+					SetText(fmt.Sprintf("[ERROR] cannot fetch rows: %s", err)).
 					SetTextStyle(textStyleError)
 
 				return
 			}
 
-			done <- true
+			q.statusTextView.SetText(q.rowsStatus(r)).SetTextStyle(textStyleDefault)
+		})
+	}()
+}
 
-			q.lastResult = r
+// closeStream stops fetching rows of the result in b.
+func (q *Query) closeStream(b *buffer) {
+	if b.cancelStream != nil {
+		b.cancelStream()
+	}
 
-			q.statusTextView.
-				SetText(
-					fmt.Sprintf(
-						"[SUCCESS] %s%d row(s), took %.2f seconds, processed %s of data",
-						msgPrefix,
-						len(r.Rows),
-						time.Since(start).Seconds(),
-						humanize.Bytes(uint64(r.TotalBytesProcessed)),
-					),
-				).
-				SetTextStyle(textStyleSuceess)
-		}
+	b.stream = nil
+	b.cancelStream = nil
+}
 
-		q.resultTextView.SetText(result)
-		q.resultTextView.ScrollToBeginning()
-	}()
+// rowsStatus describes how many rows of r are loaded.
+func (q *Query) rowsStatus(r *bigquery.Result) string {
+	if int64(len(r.Rows)) < r.TotalRows {
+		return fmt.Sprintf("%d of %d row(s) loaded", len(r.Rows), r.TotalRows)
+	}
+
+	return fmt.Sprintf("%d row(s)", len(r.Rows))
 }
 
-func (q *Query) copyQueryToClipboard() {
-	if err := clipboard.WriteAll(q.textArea.GetText()); err != nil {
-		q.statusTextView.
-			SetText(fmt.Sprintf("cannot copy query to clipboard: %s", err)).
-			SetTextStyle(textStyleError)
+// showResultMessage shows a message such as an error in the result pane of b.
+// The view is updated only if b is the current buffer.
+func (q *Query) showResultMessage(b *buffer, msg string) {
+	b.resultMessage = msg
+	b.showTable = false
 
+	if b != q.current {
 		return
 	}
 
-	q.statusTextView.SetText("copied query to clipboard").SetTextStyle(textStyleSuceess)
+	q.resultTextView.SetText(msg).ScrollToBeginning()
+	q.resultPages.SwitchToPage(resultPageMessage)
 }
 
-func (q *Query) copyResultToClipboard() {
-	if err := clipboard.WriteAll(q.resultTextView.GetText(true)); err != nil {
+// showResultTable shows rows of the result in the result pane of b. The view
+// is updated only if b is the current buffer.
+func (q *Query) showResultTable(b *buffer, r *bigquery.Result) {
+	b.showTable = true
+
+	if b != q.current {
+		return
+	}
+
+	q.resultTable.SetResult(r)
+	q.resultPages.SwitchToPage(resultPageTable)
+	q.schemaTextView.SetText(formatSchema(r.Schema)).ScrollToBeginning()
+}
+
+// toggleSchema shows or hides the schema of the result next to it.
+func (q *Query) toggleSchema() {
+	q.showSchema = !q.showSchema
+
+	if q.showSchema {
+		q.resultFlex.AddItem(q.schemaTextView, schemaPanelWidth, 0, false)
+	} else {
+		q.resultFlex.RemoveItem(q.schemaTextView)
+	}
+}
+
+// toggleFocus moves focus between the editor and the result table.
+func (q *Query) toggleFocus() {
+	if q.textArea.HasFocus() {
+		q.app.SetFocus(q.resultTable)
+	} else {
+		q.app.SetFocus(q.textArea)
+	}
+}
+
+// cancelRunningQuery cancels the query started by runQuery, if any.
+func (q *Query) cancelRunningQuery() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.cancelQuery == nil {
+		q.statusTextView.SetText("no running query").SetTextStyle(textStyleError)
+		return
+	}
+
+	q.cancelQuery()
+	q.statusTextView.SetText("cancelling query...").SetTextStyle(textStyleWarning)
+}
+
+func (q *Query) copyQueryToClipboard() {
+	if err := clipboard.WriteAll(q.textArea.GetText()); err != nil {
 		q.statusTextView.
-			SetText(fmt.Sprintf("cannot copy result to clipboard: %s", err)).
+			SetText(fmt.Sprintf("cannot copy query to clipboard: %s", err)).
 			SetTextStyle(textStyleError)
 
 		return
 	}
 
-	q.statusTextView.SetText("copied result to clipboard").SetTextStyle(textStyleSuceess)
+	q.statusTextView.SetText("copied query to clipboard").SetTextStyle(textStyleSuceess)
 }
 
-func (q *Query) copyResultToClipboardAsMarkdown() {
-	if q.lastResult == nil {
+func (q *Query) copyResultToClipboardAs(rdr renderer.Renderer, format string) {
+	if q.current.lastResult == nil {
 		q.statusTextView.SetText("nothing to copy").SetTextStyle(textStyleError)
 		return
 	}
 
-	t, err := q.markdownRenderer.Render(q.lastResult)
+	t, err := rdr.Render(q.current.lastResult)
 	if err != nil {
 		q.statusTextView.
-			SetText(fmt.Sprintf("cannot render result as Markdown table: %s", err)).
+			SetText(fmt.Sprintf("cannot render result as %s: %s", format, err)).
 			SetTextStyle(textStyleError)
 
 		return
@@ -377,37 +1153,29 @@ func (q *Query) copyResultToClipboardAsMarkdown() {
 
 	if err := clipboard.WriteAll(t); err != nil {
 		q.statusTextView.
-			SetText(fmt.Sprintf("cannot copy result to clipboard as Markdown table: %s", err)).
+			SetText(fmt.Sprintf("cannot copy result to clipboard as %s: %s", format, err)).
 			SetTextStyle(textStyleError)
 
 		return
 	}
 
-	q.statusTextView.SetText("copied result to clipboard as Markdown table").SetTextStyle(textStyleSuceess)
+	q.statusTextView.SetText(fmt.Sprintf("copied result to clipboard as %s", format)).SetTextStyle(textStyleSuceess)
 }
 
-func (q *Query) copyResultToClipboardAsTSV() {
-	if q.lastResult == nil {
+func (q *Query) copyResultSelectionToClipboard(target string, selected func() (string, bool)) {
+	t, ok := selected()
+	if !ok {
 		q.statusTextView.SetText("nothing to copy").SetTextStyle(textStyleError)
 		return
 	}
 
-	t, err := q.tsvRenderer.Render(q.lastResult)
-	if err != nil {
-		q.statusTextView.
-			SetText(fmt.Sprintf("cannot render result as TSV: %s", err)).
-			SetTextStyle(textStyleError)
-
-		return
-	}
-
 	if err := clipboard.WriteAll(t); err != nil {
 		q.statusTextView.
-			SetText(fmt.Sprintf("cannot copy result to clipboard as TSV: %s", err)).
+			SetText(fmt.Sprintf("cannot copy %s to clipboard: %s", target, err)).
 			SetTextStyle(textStyleError)
 
 		return
 	}
 
-	q.statusTextView.SetText("copied result to clipboard as TSV").SetTextStyle(textStyleSuceess)
+	q.statusTextView.SetText(fmt.Sprintf("copied %s to clipboard", target)).SetTextStyle(textStyleSuceess)
 }
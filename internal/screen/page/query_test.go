@@ -0,0 +1,1691 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"github.com/gdamore/tcell/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/bigquery/bigquerytest"
+	"github.com/dtan4/bqc/internal/catalog"
+	"github.com/dtan4/bqc/internal/checkpoint"
+	"github.com/dtan4/bqc/internal/history"
+)
+
+const (
+	testScreenWidth  = 140
+	testScreenHeight = 30
+
+	waitTimeout = 5 * time.Second
+)
+
+type queryTest struct {
+	app     *tview.Application
+	q       *Query
+	sim     tcell.SimulationScreen
+	history *history.LocalStorage
+	// bufferDir is where buffers are saved
+	bufferDir string
+}
+
+func newQueryTest(t *testing.T, bqClient bigquery.QueryBackend, opts QueryOptions) *queryTest {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	hs, err := history.NewLocalStorage(filepath.Join(dir, "history.db"), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hs.Close()
+	})
+
+	sim := tcell.NewSimulationScreen("UTF-8")
+
+	// SetScreen initializes the screen, which resets its size
+	app := tview.NewApplication().SetScreen(sim)
+	sim.SetSize(testScreenWidth, testScreenHeight)
+
+	bufferDir := filepath.Join(dir, "buffers")
+
+	q := NewQuery(app, bqClient, checkpoint.NewDir(bufferDir), hs, opts)
+
+	if cb, ok := bqClient.(bigquery.CatalogBackend); ok {
+		cc, err := catalog.NewCache(filepath.Join(dir, "catalog.db"), cb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			cc.Close()
+		})
+
+		q.SetCatalog(cc)
+	}
+	if err := q.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ps := tview.NewPages().AddPage("query", q, true, true)
+	q.SetModalFunc(func(p tview.Primitive) func() {
+		ps.AddPage("modal", p, true, true)
+
+		return func() {
+			ps.RemovePage("modal")
+		}
+	})
+
+	if cb, ok := bqClient.(bigquery.CatalogBackend); ok {
+		c := NewCatalog(app, cb)
+		if err := c.Init(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		ps.AddPage("catalog", c, true, false)
+
+		q.SetOpenCatalogFunc(func() {
+			ps.SwitchToPage("catalog")
+			c.Open()
+		})
+		c.
+			SetInsertFunc(func(name string) {
+				q.InsertText(name)
+				ps.SwitchToPage("query")
+			}).
+			SetPreviewFunc(func(name string, r *bigquery.Result) {
+				q.ShowPreview(name, r)
+				ps.SwitchToPage("query")
+			}).
+			SetDoneFunc(func() {
+				ps.SwitchToPage("query")
+			})
+	}
+
+	h := NewHistory(app, hs)
+	if err := h.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ps.AddPage("history", h, true, false)
+
+	q.SetOpenHistoryFunc(func() {
+		ps.SwitchToPage("history")
+		h.Reload()
+	})
+	h.
+		SetLoadFunc(func(query string) {
+			q.SetQuery(query)
+			ps.SwitchToPage("query")
+		}).
+		SetRenderFunc(func(r *bigquery.Result) {
+			q.ShowResult(r)
+			ps.SwitchToPage("query")
+		}).
+		SetDoneFunc(func() {
+			ps.SwitchToPage("query")
+		})
+
+	runTestApp(t, app, ps)
+
+	return &queryTest{
+		app:       app,
+		q:         q,
+		sim:       sim,
+		history:   hs,
+		bufferDir: bufferDir,
+	}
+}
+
+// runTestApp runs app with root until the test finishes.
+func runTestApp(t *testing.T, app *tview.Application, root tview.Primitive) {
+	t.Helper()
+
+	app.SetRoot(root, true)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := app.Run(); err != nil {
+			t.Errorf("run app: %s", err)
+		}
+	}()
+	t.Cleanup(func() {
+		app.Stop()
+		<-done
+	})
+}
+
+func (qt *queryTest) typeText(text string) {
+	for _, r := range text {
+		qt.sim.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+}
+
+func (qt *queryTest) ctrlX(key tcell.Key, r rune) {
+	qt.sim.InjectKey(tcell.KeyCtrlX, 0, tcell.ModCtrl)
+	qt.sim.InjectKey(key, r, tcell.ModNone)
+}
+
+// screenText returns the content of the screen. It reads the screen in the
+// application goroutine so as not to race with drawing.
+func (qt *queryTest) screenText() string {
+	var (
+		cells []tcell.SimCell
+		width int
+	)
+
+	qt.app.QueueUpdate(func() {
+		cs, w, _ := qt.sim.GetContents()
+
+		cells = append(cells, cs...)
+		width = w
+	})
+
+	var b strings.Builder
+
+	for i, c := range cells {
+		if len(c.Runes) == 0 {
+			b.WriteRune(' ')
+		} else {
+			b.WriteString(string(c.Runes))
+		}
+
+		if (i+1)%width == 0 {
+			b.WriteRune('\n')
+		}
+	}
+
+	return b.String()
+}
+
+// waitForText waits until the screen contains all of the given texts.
+func (qt *queryTest) waitForText(t *testing.T, texts ...string) {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+
+	for {
+		s := qt.screenText()
+
+		found := true
+		for _, text := range texts {
+			if !strings.Contains(s, text) {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("screen does not contain %q:\n%s", texts, s)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForTextGone waits until the screen does not contain the given text.
+func (qt *queryTest) waitForTextGone(t *testing.T, text string) {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+
+	for {
+		s := qt.screenText()
+
+		if !strings.Contains(s, text) {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("screen still contains %q:\n%s", text, s)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueryRunQuery(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1 as foo", &bigquery.Result{
+		Keys: []string{"foo"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(1)},
+		},
+		TotalBytesProcessed: 1234,
+		EndTime:             time.Date(2023, 5, 3, 12, 34, 56, 0, time.UTC),
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1 as foo")
+	qt.ctrlX(tcell.KeyEnter, 0)
+
+	qt.waitForText(t, "foo", "[SUCCESS] 1 row(s)", "processed 1.2 kB of data")
+
+	rs, err := qt.history.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs) != 1 || rs[0].Query != "select 1 as foo" {
+		t.Errorf("want the query recorded in history, got: %#v", rs)
+	}
+}
+
+func TestQueryRunQuery_historyError(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1 as foo", &bigquery.Result{
+		Keys: []string{"foo"},
+		Rows: [][]bigqueryapi.Value{
+			{big.NewRat(12345, 100)},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	// make Append fail
+	if err := qt.history.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	qt.typeText("select 1 as foo")
+	qt.ctrlX(tcell.KeyEnter, 0)
+
+	qt.waitForText(t, "123.45", "[SUCCESS] 1 row(s)", "[WARNING] not saved in history")
+}
+
+func TestQueryDryRunQuery(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+		TotalBytesProcessed: 1234,
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyRune, 'd')
+
+	qt.waitForText(t, "This query will process 1.2 kB of data.", "[SUCCESS] [dry-run] took")
+}
+
+func TestQueryRunQuery_error(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddError("select foo", errors.New("Unrecognized name: foo"))
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select foo")
+	qt.ctrlX(tcell.KeyEnter, 0)
+
+	qt.waitForText(t, "Unrecognized name: foo", "[ERROR] cannot run query")
+}
+
+func TestQueryRunQuery_errorPosition(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddError("select 1,\n  foo", bigquery.NewQueryError(&bigqueryapi.Error{
+		Reason:  "invalidQuery",
+		Message: "Unrecognized name: foo at [2:3]",
+	}))
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1,")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.typeText("  foo")
+	qt.ctrlX(tcell.KeyEnter, 0)
+
+	qt.waitForText(t, "Unrecognized name: foo at [2:3] (invalidQuery)", "[ERROR] cannot run query at line 2, column 3", "(Ln 2, Col 3)")
+
+	var bg tcell.Color
+
+	qt.app.QueueUpdate(func() {
+		// the second line of the editor, below the tab strip
+		cells, width, _ := qt.sim.GetContents()
+		_, bg, _ = cells[2*width].Style.Decompose()
+	})
+
+	if bg != lineBackgroundError {
+		t.Errorf("want the error line highlighted, got background: %v", bg)
+	}
+}
+
+func TestQueryRunStatementAtCursor(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddError("select foo", bigquery.NewQueryError(&bigqueryapi.Error{
+		Reason:  "invalidQuery",
+		Message: "Unrecognized name: foo at [1:8]",
+	}))
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1;")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.typeText("select foo;")
+	qt.ctrlX(tcell.KeyRune, 'e')
+
+	qt.waitForText(t, "[ERROR] cannot run query at line 2, column 8", "(Ln 2, Col 8)")
+
+	if diff := cmp.Diff([]string{"select foo"}, fake.Queries()); diff != "" {
+		t.Errorf("queries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryRunSelection(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 2", &bigquery.Result{
+		Keys: []string{"n"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(2)},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1; select 2")
+	for range "select 2" {
+		qt.sim.InjectKey(tcell.KeyLeft, 0, tcell.ModShift)
+	}
+	qt.ctrlX(tcell.KeyRune, 'r')
+
+	qt.waitForText(t, "[SUCCESS] 1 row(s)")
+
+	if diff := cmp.Diff([]string{"select 2"}, fake.Queries()); diff != "" {
+		t.Errorf("queries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryRunSelection_empty(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyRune, 'r')
+
+	qt.waitForText(t, "no text is selected")
+}
+
+func TestQueryCancelQuery(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{})
+	fake.Hold()
+	t.Cleanup(fake.Release)
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "running query")
+
+	qt.ctrlX(tcell.KeyRune, 'k')
+	qt.waitForText(t, "[CANCELLED]")
+
+	rs, err := qt.history.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs) != 1 || !rs[0].Cancelled {
+		t.Errorf("want the cancelled query recorded in history, got: %#v", rs)
+	}
+}
+
+func TestQueryCancelQuery_notRunning(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'k')
+
+	qt.waitForText(t, "no running query")
+}
+
+func TestQuerySortResult(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select n", &bigquery.Result{
+		Keys: []string{"n"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(20)},
+			{int64(3)},
+			{int64(100)},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select n")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 3 row(s)")
+
+	qt.ctrlX(tcell.KeyRune, 'o')
+	qt.typeText("s")
+	qt.waitForText(t, "n ▲")
+
+	s := qt.screenText()
+	if i, j, k := strings.Index(s, " 3"), strings.Index(s, " 20"), strings.Index(s, "100"); !(i < j && j < k) {
+		t.Errorf("want rows sorted in ascending order:\n%s", s)
+	}
+
+	qt.typeText("s")
+	qt.waitForText(t, "n ▼")
+
+	s = qt.screenText()
+	if i, j, k := strings.Index(s, " 3"), strings.Index(s, " 20"), strings.Index(s, "100"); !(k < j && j < i) {
+		t.Errorf("want rows sorted in descending order:\n%s", s)
+	}
+}
+
+func TestQueryShowSchema(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select id, tags", &bigquery.Result{
+		Keys: []string{"id", "tags"},
+		Schema: []*bigquery.Field{
+			{Name: "id", Type: "INTEGER", Mode: bigquery.ModeRequired},
+			{
+				Name:        "tags",
+				Type:        "RECORD",
+				Mode:        bigquery.ModeRepeated,
+				Description: "item tags",
+				Fields: []*bigquery.Field{
+					{Name: "name", Type: "STRING", Mode: bigquery.ModeNullable},
+				},
+			},
+		},
+		Rows: [][]bigqueryapi.Value{
+			{
+				int64(1),
+				[]bigqueryapi.Value{
+					[]bigqueryapi.Value{"foo"},
+				},
+			},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select id, tags")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)", "[{name: foo}]")
+
+	qt.ctrlX(tcell.KeyRune, 's')
+	qt.waitForText(t, "schema", "id      INTEGER  REQUIRED", "tags    RECORD   REPEATED  item tags", "  name  STRING   NULLABLE")
+
+	qt.ctrlX(tcell.KeyRune, 's')
+	qt.waitForTextGone(t, "REQUIRED")
+}
+
+func TestQueryFetchMoreRows(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]bigqueryapi.Value{}
+	for i := 0; i < 2500; i++ {
+		rows = append(rows, []bigqueryapi.Value{int64(i)})
+	}
+
+	fake := bigquerytest.NewFakeClient().AddResult("select n", &bigquery.Result{
+		Keys: []string{"n"},
+		Rows: rows,
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{MaxRows: 2000})
+
+	qt.typeText("select n")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1000 of 2500 row(s) loaded")
+
+	qt.ctrlX(tcell.KeyRune, 'o')
+
+	// moving the cursor to the last row fetches the next page
+	qt.typeText("G")
+	qt.waitForText(t, "2000 of 2500 row(s) loaded")
+
+	qt.typeText("m")
+	qt.waitForText(t, "reached the limit of 2000 rows")
+}
+
+func TestQueryFetchMoreRows_switchBuffer(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]bigqueryapi.Value{}
+	for i := 0; i < 1500; i++ {
+		rows = append(rows, []bigqueryapi.Value{int64(i)})
+	}
+
+	fake := bigquerytest.NewFakeClient().
+		AddResult("select n", &bigquery.Result{
+			Keys: []string{"n"},
+			Rows: rows,
+		}).
+		AddStreamError("select n", errors.New("connection reset"))
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select n")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1000 of 1500 row(s) loaded")
+
+	fake.Hold()
+
+	qt.ctrlX(tcell.KeyRune, 'o')
+	qt.typeText("G")
+	qt.waitForText(t, "fetching more rows...")
+
+	qt.ctrlX(tcell.KeyRune, 'n')
+	qt.waitForText(t, "switched to query-2")
+
+	fake.Release()
+
+	// wait for the fetch in query-1 to finish in background
+	deadline := time.Now().Add(waitTimeout)
+
+	for {
+		fetching := true
+
+		qt.app.QueueUpdate(func() {
+			fetching = qt.q.buffers[0].fetching
+		})
+
+		if !fetching {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("fetch did not finish")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	qt.ctrlX(tcell.KeyRune, '[')
+	qt.waitForText(t, "switched to query-1, [ERROR] cannot fetch rows: load result: connection reset")
+}
+
+func TestQueryConfirmCost(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		maxBytesBilled int64
+		keys           []tcell.Key
+		wantText       string
+		wantStatus     string
+		wantRuns       int
+	}{
+		"run": {
+			maxBytesBilled: 4 << 40,
+			keys:           []tcell.Key{tcell.KeyEnter},
+			wantText:       "Run it anyway?",
+			wantStatus:     "[SUCCESS] 1 row(s)",
+			wantRuns:       2,
+		},
+		"cancel": {
+			maxBytesBilled: 4 << 40,
+			keys:           []tcell.Key{tcell.KeyTab, tcell.KeyEnter},
+			wantText:       "Run it anyway?",
+			wantStatus:     "[CANCELLED] query was not run",
+			wantRuns:       1,
+		},
+		"over max bytes billed": {
+			maxBytesBilled: 1 << 40,
+			// the only button is Cancel
+			keys:       []tcell.Key{tcell.KeyEnter},
+			wantText:   "over the limit of 1.1 TB",
+			wantStatus: "[CANCELLED] query was not run",
+			wantRuns:   1,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+				Keys: []string{"foo"},
+				Rows: [][]bigqueryapi.Value{
+					{int64(1)},
+				},
+				TotalBytesProcessed: 2 << 40,
+			}).SetMaxBytesBilled(tc.maxBytesBilled)
+
+			qt := newQueryTest(t, fake, QueryOptions{
+				ConfirmBytes:   1 << 40,
+				MaxBytesBilled: tc.maxBytesBilled,
+				PricePerTiB:    6.25,
+			})
+
+			qt.typeText("select 1")
+			qt.ctrlX(tcell.KeyEnter, 0)
+			qt.waitForText(t, "This query will process 2.2 TB of data", "$12.50", tc.wantText)
+
+			for _, k := range tc.keys {
+				qt.sim.InjectKey(k, 0, tcell.ModNone)
+			}
+
+			qt.waitForText(t, tc.wantStatus)
+
+			if got := len(fake.Queries()); got != tc.wantRuns {
+				t.Errorf("want %d call(s) to BigQuery, got: %d", tc.wantRuns, got)
+			}
+		})
+	}
+}
+
+func TestQueryConfirmCost_cancelQuery(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+		Keys: []string{"foo"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(1)},
+		},
+		TotalBytesProcessed: 2 << 40,
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{
+		ConfirmBytes: 1 << 40,
+		PricePerTiB:  6.25,
+	})
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "Run it anyway?")
+
+	// the dialog has focus, so cancel the query like Ctrl-X K does
+	qt.app.QueueUpdateDraw(qt.q.cancelRunningQuery)
+
+	qt.waitForTextGone(t, "Run it anyway?")
+	qt.waitForText(t, "[CANCELLED]")
+
+	if got := len(fake.Queries()); got != 1 {
+		t.Errorf("want only the dry run sent to BigQuery, got: %d call(s)", got)
+	}
+}
+
+func TestQueryConfirmCost_belowThreshold(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+		Keys: []string{"foo"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(1)},
+		},
+		TotalBytesProcessed: 1 << 20,
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{
+		ConfirmBytes: 1 << 40,
+	})
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)")
+}
+
+func TestQueryBuffers(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddResult("select 1 as foo", &bigquery.Result{
+			Keys: []string{"foo"},
+			Rows: [][]bigqueryapi.Value{{int64(1)}},
+		}).
+		AddResult("select 2 as bar", &bigquery.Result{
+			Keys: []string{"bar"},
+			Rows: [][]bigqueryapi.Value{{int64(2)}},
+		})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.waitForText(t, "1:query-1")
+
+	qt.typeText("select 1 as foo")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)")
+
+	qt.ctrlX(tcell.KeyRune, 'n')
+	qt.waitForText(t, "2:query-2", "switched to query-2")
+	qt.waitForTextGone(t, "select 1 as foo")
+
+	qt.typeText("select 2 as bar")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "bar", "[SUCCESS] 1 row(s)")
+
+	// each buffer keeps its own query and result
+	qt.ctrlX(tcell.KeyRune, '[')
+	qt.waitForText(t, "select 1 as foo", "switched to query-1")
+	qt.waitForTextGone(t, "select 2 as bar")
+	qt.waitForTextGone(t, "bar")
+
+	// the buffer switched from is saved without closing the page
+	got, err := os.ReadFile(filepath.Join(qt.bufferDir, "query-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "select 2 as bar" {
+		t.Errorf("buffer query-2: want saved on switch, got %q", string(got))
+	}
+
+	// a new buffer is opened next to the current one
+	qt.ctrlX(tcell.KeyRune, 'n')
+	qt.waitForText(t, "2:query-3", "3:query-2")
+
+	qt.ctrlX(tcell.KeyRune, '3')
+	qt.waitForText(t, "select 2 as bar", "switched to query-2")
+
+	qt.app.QueueUpdate(func() {
+		err = qt.q.Close()
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"query-1": "select 1 as foo",
+		"query-2": "select 2 as bar",
+	} {
+		got, err := os.ReadFile(filepath.Join(qt.bufferDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != want {
+			t.Errorf("buffer %s: want %q, got %q", name, want, string(got))
+		}
+	}
+
+	names, err := checkpoint.NewDir(qt.bufferDir).Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"query-1", "query-3", "query-2"}, names); diff != "" {
+		t.Errorf("buffer order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryCloseBuffer(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'w')
+	qt.waitForText(t, "cannot close the last buffer")
+
+	qt.ctrlX(tcell.KeyRune, 'n')
+	qt.typeText("select 2")
+	qt.waitForText(t, "2:query-2")
+
+	qt.ctrlX(tcell.KeyRune, 'w')
+	qt.waitForText(t, "Close buffer query-2?")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+
+	qt.waitForText(t, "closed buffer query-2")
+	qt.waitForTextGone(t, "2:query-2")
+	qt.waitForTextGone(t, "select 2")
+}
+
+func TestQueryCloseBuffer_runningQuery(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddResult("select 1", &bigquery.Result{
+			Keys: []string{"foo"},
+			Rows: [][]bigqueryapi.Value{{int64(1)}},
+		}).
+		AddResult("select 2", &bigquery.Result{
+			Keys: []string{"bar"},
+			Rows: [][]bigqueryapi.Value{{int64(2)}},
+		})
+	fake.Hold()
+	t.Cleanup(fake.Release)
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1")
+
+	qt.ctrlX(tcell.KeyRune, 'n')
+	qt.typeText("select 2")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "running query")
+
+	// closing the buffer cancels its query
+	qt.ctrlX(tcell.KeyRune, 'w')
+	qt.waitForText(t, "Close buffer query-2?")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[CANCELLED]")
+
+	fake.Release()
+
+	// another query can run
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "foo", "[SUCCESS] 1 row(s)")
+}
+
+func TestQuerySyntaxHighlight(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	qt.typeText("select 'a', f(1) -- c")
+	qt.waitForText(t, "select 'a', f(1) -- c")
+
+	testcases := map[string]struct {
+		column int
+		want   tcell.Color
+	}{
+		"keyword":  {column: 0, want: tcell.ColorDodgerBlue},
+		"string":   {column: 7, want: tcell.ColorDarkOrange},
+		"function": {column: 12, want: tcell.ColorMediumPurple},
+		"number":   {column: 14, want: tcell.ColorDarkOrange},
+		"comment":  {column: 17, want: tcell.ColorGray},
+	}
+
+	var fgs []tcell.Color
+
+	qt.app.QueueUpdate(func() {
+		// the first line of the editor, below the tab strip
+		cells, width, _ := qt.sim.GetContents()
+
+		for _, c := range cells[width : 2*width] {
+			fg, _, _ := c.Style.Decompose()
+			fgs = append(fgs, fg)
+		}
+	})
+
+	for name, tc := range testcases {
+		if fgs[tc.column] != tc.want {
+			t.Errorf("%s: want color %v, got: %v", name, tc.want, fgs[tc.column])
+		}
+	}
+}
+
+func TestQueryCompletion(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddTable(&bigquery.Table{
+			DatasetID: "shop",
+			TableID:   "orders",
+			Schema: []*bigquery.Field{
+				{Name: "id", Type: "INTEGER"},
+				{Name: "status", Type: "STRING"},
+			},
+		}).
+		AddTable(&bigquery.Table{DatasetID: "shop", TableID: "users"})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	// keywords
+	qt.typeText("sel")
+	qt.waitForText(t, "SELECT keyword")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.waitForText(t, "select ")
+	qt.waitForTextGone(t, "SELECT keyword")
+
+	// tables after FROM
+	qt.typeText(" * from shop.")
+	qt.waitForText(t, "orders table", "users  table")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.waitForText(t, "select * from shop.orders")
+
+	// columns after alias
+	qt.typeText(" o where o.st")
+	qt.waitForText(t, "status column")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.waitForText(t, "select * from shop.orders o where o.status")
+
+	// closed by Esc
+	qt.typeText(" and o.")
+	qt.waitForText(t, "id     column")
+	qt.sim.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "id     column")
+}
+
+func TestQueryCompletion_enter(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	// Enter inserts a newline while the popup is opened by typing
+	qt.typeText("sel")
+	qt.waitForText(t, "SELECT keyword")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "SELECT keyword")
+
+	var text string
+
+	qt.app.QueueUpdate(func() {
+		text = qt.q.textArea.GetText()
+	})
+
+	if text != "sel\n" {
+		t.Errorf("want a newline inserted, got: %q", text)
+	}
+
+	// Enter accepts the candidate in the popup opened by Ctrl-Space, even after
+	// narrowing it by typing
+	qt.typeText("fr")
+	qt.waitForText(t, "FROM_BASE64 function")
+	qt.sim.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "FROM_BASE64 function")
+	qt.sim.InjectKey(tcell.KeyCtrlSpace, 0, tcell.ModCtrl)
+	qt.waitForText(t, "FROM_BASE64 function")
+	qt.typeText("om")
+	qt.waitForText(t, "from")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "FROM_BASE64 function")
+
+	qt.app.QueueUpdate(func() {
+		text = qt.q.textArea.GetText()
+	})
+
+	if text != "sel\nfrom" {
+		t.Errorf("want the candidate accepted, got: %q", text)
+	}
+}
+
+func TestQueryCompletion_offsetPage(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	qt.typeText("sel")
+	qt.waitForText(t, "SELECT keyword")
+
+	const (
+		px, py, pw, ph = 90, 15, 40, 12
+	)
+
+	var (
+		cells []tcell.SimCell
+		width int
+	)
+
+	// draw the page away from the origin of another screen
+	qt.app.QueueUpdate(func() {
+		sim := tcell.NewSimulationScreen("UTF-8")
+		if err := sim.Init(); err != nil {
+			t.Error(err)
+			return
+		}
+		defer sim.Fini()
+
+		sim.SetSize(testScreenWidth, testScreenHeight)
+
+		qt.q.SetRect(px, py, pw, ph)
+		qt.q.Draw(sim)
+		sim.Show()
+
+		cells, width, _ = sim.GetContents()
+	})
+
+	found := false
+
+	for i, c := range cells {
+		x, y := i%width, i/width
+
+		if c.Style != completionStyle && c.Style != completionSelectedStyle {
+			continue
+		}
+
+		found = true
+
+		if x < px || x >= px+pw || y < py || y >= py+ph {
+			t.Fatalf("want the popup inside the page, got a cell at (%d, %d)", x, y)
+		}
+	}
+
+	if !found {
+		t.Error("want the popup drawn")
+	}
+}
+
+func TestQueryCompletion_missingTable(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient()
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	// columns of a table which doesn't exist are fetched once while typing,
+	// and the failure is not reported
+	qt.typeText("select * from shop.missing where wh")
+	qt.waitForText(t, "WHERE keyword")
+	qt.sim.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	qt.typeText(" and x")
+	qt.waitForText(t, "where wh and x")
+
+	if s := qt.screenText(); strings.Contains(s, "cannot fetch names") {
+		t.Errorf("want no warning while typing, got:\n%s", s)
+	}
+
+	if n := fake.TableGets(); n != 1 {
+		t.Errorf("want the table fetched once, got: %d", n)
+	}
+
+	// reported when asked by Ctrl-Space
+	qt.sim.InjectKey(tcell.KeyCtrlSpace, 0, tcell.ModCtrl)
+	qt.waitForText(t, "cannot fetch names for completion: table shop.missing is not found")
+}
+
+func TestQueryCatalog(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddTable(&bigquery.Table{
+			DatasetID:    "shop",
+			TableID:      "orders",
+			Type:         "TABLE",
+			Partitioning: "DAY by created_at",
+			Clustering:   []string{"user_id", "status"},
+			Description:  "[beta] orders placed online",
+			NumRows:      1234567,
+			NumBytes:     2048,
+			Schema: []*bigquery.Field{
+				{Name: "id", Type: "INTEGER", Mode: bigquery.ModeRequired},
+			},
+		}).
+		AddTable(&bigquery.Table{DatasetID: "shop", TableID: "users"}).
+		AddTable(&bigquery.Table{DatasetID: "logs", TableID: "access"})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select * from ")
+
+	qt.ctrlX(tcell.KeyRune, 'b')
+	qt.waitForText(t, "shop", "logs", "dataset fake-project.shop")
+
+	// tables are listed when the dataset is expanded
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "orders", "users", "2 table(s) in shop")
+
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	qt.waitForText(t,
+		"fake-project.shop.orders",
+		"rows          1,234,567",
+		"size          2.0 kB",
+		"partitioning  DAY by created_at",
+		"clustering    user_id, status",
+		"description   [beta] orders placed online",
+		"id  INTEGER  REQUIRED",
+	)
+
+	qt.typeText("i")
+	qt.waitForText(t, "select * from `fake-project.shop.orders`")
+}
+
+func TestQueryCatalogPreview(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddTable(&bigquery.Table{DatasetID: "shop", TableID: "orders"}).
+		AddPreview("shop", "orders", &bigquery.Result{
+			Keys:      []string{"id", "status"},
+			Rows:      [][]bigqueryapi.Value{{int64(1), "paid"}, {int64(2), "shipped"}},
+			TotalRows: 42,
+		})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'b')
+	qt.waitForText(t, "dataset fake-project.shop")
+
+	// datasets cannot be previewed
+	qt.typeText("p")
+	qt.waitForText(t, "select a table to preview")
+
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "1 table(s) in shop")
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+
+	qt.typeText("p")
+	qt.waitForText(t, "shipped", "[PREVIEW] 2 of 42 row(s) loaded of fake-project.shop.orders, no bytes billed")
+
+	if qs := fake.Queries(); len(qs) != 0 {
+		t.Errorf("want no query run, got: %q", qs)
+	}
+}
+
+func TestQueryJobDetails(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+		Keys:             []string{"f0_"},
+		Rows:             [][]bigqueryapi.Value{{int64(1)}},
+		JobID:            "job_abc",
+		TotalBytesBilled: 10000000,
+		SlotMillis:       1500,
+		Stages: []*bigquery.Stage{
+			{Name: "S00: Output", Status: "COMPLETE", StartTime: start, EndTime: start.Add(time.Second)},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'i')
+	qt.waitForText(t, "no job details to show")
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)", "billed 10 MB, 1.5s of slot time")
+
+	qt.ctrlX(tcell.KeyRune, 'i')
+	qt.waitForText(t, "job details", "job_abc", "S00: Output dominates the plan")
+
+	qt.sim.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "job_abc")
+}
+
+func TestQueryScript(t *testing.T) {
+	t.Parallel()
+
+	script := "declare n int64 default 2; select n; select n * 10 as m"
+
+	fake := bigquerytest.NewFakeClient().AddResult(script, &bigquery.Result{
+		Keys:          []string{"m"},
+		Rows:          [][]bigqueryapi.Value{{int64(20)}},
+		StatementType: bigquery.StatementTypeScript,
+		Children: []*bigquery.Result{
+			{
+				Query:         "declare n int64 default 2",
+				StatementType: "DECLARE",
+			},
+			{
+				Query:               "select n",
+				Keys:                []string{"n"},
+				Rows:                [][]bigqueryapi.Value{{int64(2)}},
+				TotalRows:           1,
+				TotalBytesProcessed: 1000,
+				StatementType:       bigquery.StatementTypeSelect,
+			},
+			{
+				Query:         "select n * 10 as m",
+				Keys:          []string{"m"},
+				Rows:          [][]bigqueryapi.Value{{int64(20)}},
+				TotalRows:     1,
+				StatementType: bigquery.StatementTypeSelect,
+			},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'v')
+	qt.waitForText(t, "the last query is not a script")
+
+	qt.typeText(script)
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS]", "ran 3 statement(s), press Ctrl-X V to show each")
+
+	// statements are read when the list is opened
+	if got := fake.ChildReads(); got != 0 {
+		t.Errorf("want no statements read by running the script, got %d read(s)", got)
+	}
+
+	qt.ctrlX(tcell.KeyRune, 'v')
+	qt.waitForText(t, "script statements", "1. DECLARE: declare n int64 default 2", "2. SELECT: select n")
+
+	// the second statement
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SCRIPT] statement 2 of 3, SELECT, 1 row(s), processed 1.0 kB of data")
+	qt.waitForTextGone(t, "script statements")
+
+	var keys []string
+
+	qt.app.QueueUpdate(func() {
+		keys = qt.q.current.lastResult.Keys
+	})
+
+	if diff := cmp.Diff([]string{"n"}, keys); diff != "" {
+		t.Errorf("shown result differs: (-want +got)\n%s", diff)
+	}
+
+	qt.ctrlX(tcell.KeyRune, 'v')
+	qt.waitForText(t, "final result (SCRIPT)")
+	qt.sim.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "script statements")
+
+	if got := fake.ChildReads(); got != 1 {
+		t.Errorf("want statements read once, got %d read(s)", got)
+	}
+}
+
+func TestQueryScript_cancelStatements(t *testing.T) {
+	t.Parallel()
+
+	script := "select 1; select 2"
+
+	fake := bigquerytest.NewFakeClient().AddResult(script, &bigquery.Result{
+		Keys:          []string{"n"},
+		Rows:          [][]bigqueryapi.Value{{int64(2)}},
+		StatementType: bigquery.StatementTypeScript,
+		Children: []*bigquery.Result{
+			{Query: "select 1", StatementType: bigquery.StatementTypeSelect},
+			{Query: "select 2", StatementType: bigquery.StatementTypeSelect},
+		},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText(script)
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "ran 2 statement(s)")
+
+	fake.Hold()
+	t.Cleanup(fake.Release)
+
+	qt.ctrlX(tcell.KeyRune, 'v')
+	qt.waitForText(t, "reading results of statements...")
+
+	qt.ctrlX(tcell.KeyRune, 'k')
+	qt.waitForText(t, "[CANCELLED] results of statements were not read")
+
+	// the statements are read again after cancelling
+	fake.Release()
+
+	qt.ctrlX(tcell.KeyRune, 'v')
+	qt.waitForText(t, "script statements", "1. SELECT: select 1")
+}
+
+func TestQuerySession(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		EnableSession().
+		AddResult("select 1", &bigquery.Result{Keys: []string{"f0_"}, Rows: [][]bigqueryapi.Value{{int64(1)}}})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.waitForText(t, "session: not started")
+
+	qt.ctrlX(tcell.KeyRune, 'a')
+	qt.waitForText(t, "no session is created yet")
+
+	runQuery := func(n int) {
+		t.Helper()
+
+		qt.ctrlX(tcell.KeyEnter, 0)
+
+		deadline := time.Now().Add(waitTimeout)
+		for len(fake.Queries()) < n || qt.q.isQueryRunning() {
+			if time.Now().After(deadline) {
+				t.Fatalf("query #%d is not run", n)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	qt.typeText("select 1")
+	runQuery(1)
+	qt.waitForText(t, "session: fake-session…")
+
+	// TEMP tables created by the first query can be used by the second one
+	runQuery(2)
+
+	qt.ctrlX(tcell.KeyRune, 'a')
+	qt.waitForText(t, "End session fake-session-1?")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SESSION] ended session fake-session-1", "session: not started")
+
+	runQuery(3)
+
+	if diff := cmp.Diff([]string{"fake-session-1", "fake-session-1", "fake-session-2"}, fake.Sessions()); diff != "" {
+		t.Errorf("sessions of queries differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestQuerySession_disabled(t *testing.T) {
+	t.Parallel()
+
+	qt := newQueryTest(t, bigquerytest.NewFakeClient(), QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'a')
+	qt.waitForText(t, "session mode is disabled, run bqc with -session")
+	qt.waitForTextGone(t, "session: not started")
+}
+
+func TestQuerySaveResult(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+		Keys: []string{"f0_"},
+		Rows: [][]bigqueryapi.Value{{int64(1)}, {int64(2)}, {int64(3)}},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.ctrlX(tcell.KeyRune, 'p')
+	qt.waitForText(t, "no query to save the result of")
+
+	qt.typeText("select 1")
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "[SUCCESS] 3 row(s)")
+
+	qt.ctrlX(tcell.KeyRune, 'p')
+	qt.waitForText(t, "save result into [project.]dataset.table")
+	qt.typeText("sales.daily")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SAVED] 3 row(s) written into fake-project.sales.daily")
+
+	// the table exists, so the default "fail" makes saving fail
+	qt.ctrlX(tcell.KeyRune, 'p')
+	qt.waitForText(t, "save result into [project.]dataset.table")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[ERROR] cannot save result into fake-project.sales.daily: table fake-project.sales.daily already exists")
+
+	// choose "append"
+	qt.ctrlX(tcell.KeyRune, 'p')
+	qt.waitForText(t, "save result into [project.]dataset.table")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SAVED] 3 row(s) written into fake-project.sales.daily")
+
+	want := []bigquery.Destination{
+		{ProjectID: bigquerytest.FakeProjectID, DatasetID: "sales", TableID: "daily", WriteDisposition: bigquery.WriteEmpty},
+		{ProjectID: bigquerytest.FakeProjectID, DatasetID: "sales", TableID: "daily", WriteDisposition: bigquery.WriteAppend},
+	}
+
+	if diff := cmp.Diff(want, fake.Destinations()); diff != "" {
+		t.Errorf("destinations differ: (-want +got)\n%s", diff)
+	}
+
+	// the shown result is kept
+	qt.waitForText(t, "f0_")
+
+	// saves are recorded in history after the run
+	rs, err := qt.history.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, e := range rs {
+		r, err := qt.history.Load(e.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, r.Destination)
+	}
+
+	if diff := cmp.Diff([]string{"", "fake-project.sales.daily", "fake-project.sales.daily"}, got); diff != "" {
+		t.Errorf("destinations in history differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestQuerySaveResult_script(t *testing.T) {
+	t.Parallel()
+
+	script := "declare n int64 default 1;\nselect n"
+
+	fake := bigquerytest.NewFakeClient().AddResult(script, &bigquery.Result{
+		Keys:          []string{"n"},
+		Rows:          [][]bigqueryapi.Value{{int64(1)}},
+		StatementType: bigquery.StatementTypeScript,
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	// the whole script is saved even with the cursor on a statement
+	qt.typeText("declare n int64 default 1;")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.typeText("select n")
+
+	qt.ctrlX(tcell.KeyRune, 'p')
+	qt.waitForText(t, "save result into [project.]dataset.table")
+	qt.typeText("sales.n")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SAVED] 1 row(s) written into fake-project.sales.n")
+
+	if diff := cmp.Diff([]string{script}, fake.Queries()); diff != "" {
+		t.Errorf("queries differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestQuerySaveResult_confirmCost(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{
+		Keys:                []string{"f0_"},
+		Rows:                [][]bigqueryapi.Value{{int64(1)}},
+		TotalBytesProcessed: 2 << 40,
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{
+		ConfirmBytes: 1 << 40,
+		PricePerTiB:  6.25,
+	})
+
+	qt.typeText("select 1")
+
+	qt.ctrlX(tcell.KeyRune, 'p')
+	qt.waitForText(t, "save result into [project.]dataset.table")
+	qt.typeText("sales.daily")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "This query will process 2.2 TB of data", "Run it anyway?")
+
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[CANCELLED] result was not saved")
+
+	if got := fake.Destinations(); len(got) != 0 {
+		t.Errorf("want nothing saved, got: %v", got)
+	}
+}
+
+func TestQueryLiveDryRun(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().
+		AddResult("select 1", &bigquery.Result{TotalBytesProcessed: 1200}).
+		AddError("select foo", bigquery.NewQueryError(&bigqueryapi.Error{
+			Reason:  "invalidQuery",
+			Message: "Unrecognized name: foo at [1:8]",
+		}))
+
+	qt := newQueryTest(t, fake, QueryOptions{LiveDryRunDelay: 20 * time.Millisecond})
+
+	qt.typeText("select 1")
+	qt.waitForText(t, "[DRY RUN] this statement will process 1.2 kB of data")
+
+	// the statement at the cursor is dry-run, and the error is located in the
+	// editor
+	qt.typeText(";")
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.typeText("select foo")
+	qt.waitForText(t, "[DRY RUN] Unrecognized name: foo at [2:8]")
+
+	rs, err := qt.history.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs) != 0 {
+		t.Errorf("want live dry runs not recorded in history, got: %#v", rs)
+	}
+}
+
+func TestQueryLiveDryRun_disabled(t *testing.T) {
+	t.Parallel()
+
+	fake := bigquerytest.NewFakeClient().AddResult("select 1", &bigquery.Result{TotalBytesProcessed: 1200})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText("select 1")
+	qt.waitForText(t, "select 1")
+
+	time.Sleep(100 * time.Millisecond)
+
+	if qs := fake.Queries(); len(qs) != 0 {
+		t.Errorf("want no dry run, got: %q", qs)
+	}
+}
+
+func TestQueryParameters(t *testing.T) {
+	t.Parallel()
+
+	query := "select @n, @s"
+
+	fake := bigquerytest.NewFakeClient().AddResult(query, &bigquery.Result{
+		Keys: []string{"n", "s"},
+		Rows: [][]bigqueryapi.Value{{int64(42), "abc"}},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	qt.typeText(query)
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "query parameters (2)", "@n", "@s")
+
+	// @n = 42 as INT64, @s = abc as STRING
+	qt.typeText("42")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.typeText("abc")
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)")
+
+	want := []bigquery.Parameter{
+		{Name: "n", Type: "INT64", Value: "42"},
+		{Name: "s", Type: "STRING", Value: "abc"},
+	}
+
+	if diff := cmp.Diff([][]bigquery.Parameter{want}, fake.Parameters()); diff != "" {
+		t.Errorf("parameters: -want +got:\n%s", diff)
+	}
+
+	rs, err := qt.history.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs) != 1 {
+		t.Fatalf("want 1 history entry, got: %d", len(rs))
+	}
+
+	if diff := cmp.Diff(want, rs[0].Parameters); diff != "" {
+		t.Errorf("parameters in history: -want +got:\n%s", diff)
+	}
+
+	// the last values are filled in
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "query parameters (2)", "INT64")
+
+	for range 4 {
+		qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	}
+
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForTextGone(t, "query parameters (2)")
+
+	deadline := time.Now().Add(waitTimeout)
+	for len(fake.Queries()) < 2 || qt.q.isQueryRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("query is not run again")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// cancelled
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "query parameters (2)")
+	qt.sim.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	qt.waitForText(t, "[CANCELLED] query was not run")
+
+	if diff := cmp.Diff([][]bigquery.Parameter{want, want}, fake.Parameters()); diff != "" {
+		t.Errorf("parameters: -want +got:\n%s", diff)
+	}
+}
+
+func TestQueryParameters_history(t *testing.T) {
+	t.Parallel()
+
+	query := "select @n"
+
+	fake := bigquerytest.NewFakeClient().AddResult(query, &bigquery.Result{
+		Keys: []string{"n"},
+		Rows: [][]bigqueryapi.Value{{int64(42)}},
+	})
+
+	qt := newQueryTest(t, fake, QueryOptions{})
+
+	// given in an earlier session
+	if err := qt.history.Append(&bigquery.Result{
+		Query:      query,
+		Parameters: []bigquery.Parameter{{Name: "n", Type: "INT64", Value: "42"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	qt.typeText(query)
+	qt.ctrlX(tcell.KeyEnter, 0)
+	qt.waitForText(t, "query parameters (1)", "42", "INT64")
+
+	for range 2 {
+		qt.sim.InjectKey(tcell.KeyTab, 0, tcell.ModNone)
+	}
+
+	qt.sim.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	qt.waitForText(t, "[SUCCESS] 1 row(s)")
+
+	want := [][]bigquery.Parameter{
+		{{Name: "n", Type: "INT64", Value: "42"}},
+	}
+
+	if diff := cmp.Diff(want, fake.Parameters()); diff != "" {
+		t.Errorf("parameters: -want +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,362 @@
+package page
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/renderer"
+)
+
+// resultTable is a navigable grid of a query result. The header row is pinned
+// and the current cell is highlighted.
+type resultTable struct {
+	*tview.Table
+
+	content *resultContent
+}
+
+func newResultTable() *resultTable {
+	t := &resultTable{
+		Table:   tview.NewTable(),
+		content: &resultContent{sortColumn: -1},
+	}
+
+	t.SetContent(t.content).
+		SetFixed(1, 0).
+		SetSelectable(true, true).
+		SetSelectedStyle(textStyleDefault.Reverse(true)).
+		SetSeparator(tview.Borders.Vertical)
+
+	return t
+}
+
+// SetResult replaces the content with the given result.
+func (t *resultTable) SetResult(r *bigquery.Result) {
+	t.content.SetResult(r)
+	t.Select(1, 0).ScrollToBeginning()
+}
+
+// AppendRows adds rows fetched later to the end. Rows are sorted again if the
+// table is sorted.
+func (t *resultTable) AppendRows(rows [][]bigqueryapi.Value) {
+	t.content.AppendRows(rows)
+}
+
+// SortBySelectedColumn sorts rows by the selected column. Sorting by the same
+// column again toggles the order.
+func (t *resultTable) SortBySelectedColumn() {
+	_, column := t.GetSelection()
+
+	t.content.SortBy(column)
+}
+
+// SelectedCell returns the text of the selected cell.
+func (t *resultTable) SelectedCell() (string, bool) {
+	row, column := t.GetSelection()
+
+	return t.content.Value(row-1, column)
+}
+
+// SelectedRow returns the values of the selected row separated by tabs.
+func (t *resultTable) SelectedRow() (string, bool) {
+	row, _ := t.GetSelection()
+
+	if row < 1 || row > len(t.content.rows) {
+		return "", false
+	}
+
+	vs := make([]string, 0, len(t.content.keys))
+	for i := range t.content.keys {
+		v, _ := t.content.Value(row-1, i)
+		vs = append(vs, v)
+	}
+
+	return strings.Join(vs, "\t"), true
+}
+
+// SelectedColumn returns the values of the selected column separated by
+// newlines.
+func (t *resultTable) SelectedColumn() (string, bool) {
+	_, column := t.GetSelection()
+
+	if column < 0 || column >= len(t.content.keys) || len(t.content.rows) == 0 {
+		return "", false
+	}
+
+	vs := make([]string, 0, len(t.content.rows))
+	for i := range t.content.rows {
+		v, _ := t.content.Value(i, column)
+		vs = append(vs, v)
+	}
+
+	return strings.Join(vs, "\n"), true
+}
+
+// resultContent serves table cells from the result without creating all of
+// them upfront.
+type resultContent struct {
+	tview.TableContentReadOnly
+
+	keys   []string
+	schema []*bigquery.Field
+	// rows are in display order, which may differ from the original result
+	rows [][]bigqueryapi.Value
+
+	sortColumn int
+	sortDesc   bool
+}
+
+var _ tview.TableContent = (*resultContent)(nil)
+
+func (c *resultContent) SetResult(r *bigquery.Result) {
+	c.keys = r.Keys
+	c.schema = r.Schema
+	c.rows = append([][]bigqueryapi.Value{}, r.Rows...)
+	c.sortColumn = -1
+	c.sortDesc = false
+}
+
+func (c *resultContent) AppendRows(rows [][]bigqueryapi.Value) {
+	c.rows = append(c.rows, rows...)
+
+	if c.sortColumn >= 0 {
+		c.sort()
+	}
+}
+
+func (c *resultContent) SortBy(column int) {
+	if column < 0 || column >= len(c.keys) {
+		return
+	}
+
+	if c.sortColumn == column {
+		c.sortDesc = !c.sortDesc
+	} else {
+		c.sortColumn = column
+		c.sortDesc = false
+	}
+
+	c.sort()
+}
+
+func (c *resultContent) sort() {
+	keys := c.sortKeys(c.sortColumn)
+
+	order := make([]int, len(c.rows))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if c.sortDesc {
+			return compareValues(keys[order[j]], keys[order[i]]) < 0
+		}
+
+		return compareValues(keys[order[i]], keys[order[j]]) < 0
+	})
+
+	rows := make([][]bigqueryapi.Value, len(c.rows))
+	for i, o := range order {
+		rows[i] = c.rows[o]
+	}
+
+	c.rows = rows
+}
+
+// sortKeys returns values of the column converted once for compareValues, so
+// that numbers in the column have the same type. Numbers of different types
+// are found in results recorded without the schema.
+func (c *resultContent) sortKeys(column int) []bigqueryapi.Value {
+	keys := make([]bigqueryapi.Value, len(c.rows))
+
+	hasFloat, hasRat := false, false
+
+	for i := range c.rows {
+		v := c.cellValue(i, column)
+
+		switch v := v.(type) {
+		case int:
+			keys[i] = int64(v)
+		case float64:
+			hasFloat = true
+			keys[i] = v
+		case *big.Rat:
+			hasRat = true
+			keys[i] = v
+		default:
+			keys[i] = v
+		}
+	}
+
+	if !hasFloat && !hasRat {
+		return keys
+	}
+
+	for i, k := range keys {
+		switch k := k.(type) {
+		case int64:
+			if hasFloat {
+				keys[i] = float64(k)
+			} else {
+				keys[i] = new(big.Rat).SetInt64(k)
+			}
+		case *big.Rat:
+			if hasFloat {
+				keys[i], _ = k.Float64()
+			}
+		}
+	}
+
+	return keys
+}
+
+// Value returns the text of the cell at the given row (0-based, excluding the
+// header) and column.
+func (c *resultContent) Value(row, column int) (string, bool) {
+	if row < 0 || row >= len(c.rows) || column < 0 || column >= len(c.keys) {
+		return "", false
+	}
+
+	return renderer.FormatValue(c.field(column), c.cellValue(row, column)), true
+}
+
+// cellValue returns the value at the given row (0-based, excluding the header)
+// and column. Missing values are treated as NULL.
+func (c *resultContent) cellValue(row, column int) bigqueryapi.Value {
+	if column >= len(c.rows[row]) {
+		return nil
+	}
+
+	return c.rows[row][column]
+}
+
+// field returns the schema of the given column, or nil if it is unknown.
+func (c *resultContent) field(column int) *bigquery.Field {
+	if column < 0 || column >= len(c.schema) {
+		return nil
+	}
+
+	return c.schema[column]
+}
+
+func (c *resultContent) GetCell(row, column int) *tview.TableCell {
+	if column < 0 || column >= len(c.keys) {
+		return nil
+	}
+
+	if row == 0 {
+		title := c.keys[column]
+
+		if column == c.sortColumn {
+			if c.sortDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+
+		return tview.NewTableCell(tview.Escape(title)).
+			SetStyle(textStyleDefault.Bold(true)).
+			SetSelectable(false)
+	}
+
+	if row-1 >= len(c.rows) {
+		return nil
+	}
+
+	f := c.field(column)
+	v := c.cellValue(row-1, column)
+
+	cell := tview.NewTableCell(tview.Escape(renderer.FormatValue(f, v)))
+
+	if (f != nil && f.IsNumeric()) || (f == nil && isNumeric(v)) {
+		cell.SetAlign(tview.AlignRight)
+	}
+
+	if v == nil {
+		cell.SetStyle(textStyleNull)
+	}
+
+	return cell
+}
+
+func (c *resultContent) GetRowCount() int {
+	if len(c.keys) == 0 {
+		return 0
+	}
+
+	return len(c.rows) + 1
+}
+
+func (c *resultContent) GetColumnCount() int {
+	return len(c.keys)
+}
+
+func isNumeric(v bigqueryapi.Value) bool {
+	switch v.(type) {
+	case int, int64, float64, *big.Rat:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareValues compares sort keys of the same column. NULL comes first, and
+// NaN comes next as in BigQuery. Values of unknown types are compared by their
+// string representation.
+func compareValues(a, b bigqueryapi.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch av := a.(type) {
+	case int64:
+		if bv, ok := b.(int64); ok {
+			return cmp.Compare(av, bv)
+		}
+	case float64:
+		if bv, ok := b.(float64); ok {
+			// NaN is less than any other number, including -Inf
+			return cmp.Compare(av, bv)
+		}
+	case *big.Rat:
+		if bv, ok := b.(*big.Rat); ok {
+			return av.Cmp(bv)
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0
+			case !av:
+				return -1
+			default:
+				return 1
+			}
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Compare(bv)
+		}
+	}
+
+	// civil.Date, civil.DateTime and civil.Time are formatted in sortable forms
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
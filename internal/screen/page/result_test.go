@@ -0,0 +1,201 @@
+package page
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	bigqueryapi "cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+func TestResultContentSortBy(t *testing.T) {
+	t.Parallel()
+
+	c := &resultContent{sortColumn: -1}
+	c.SetResult(&bigquery.Result{
+		Keys: []string{"name", "score"},
+		Rows: [][]bigqueryapi.Value{
+			{"b", big.NewRat(15, 10)},
+			{"a", nil},
+			{"c", big.NewRat(-2, 1)},
+		},
+	})
+
+	names := func() []string {
+		ns := []string{}
+		for i := range c.rows {
+			v, _ := c.Value(i, 0)
+			ns = append(ns, v)
+		}
+		return ns
+	}
+
+	c.SortBy(1)
+
+	if diff := cmp.Diff([]string{"a", "c", "b"}, names()); diff != "" {
+		t.Errorf("ascending order mismatch (-want +got):\n%s", diff)
+	}
+
+	c.SortBy(1)
+
+	if diff := cmp.Diff([]string{"b", "c", "a"}, names()); diff != "" {
+		t.Errorf("descending order mismatch (-want +got):\n%s", diff)
+	}
+
+	c.SortBy(0)
+
+	if diff := cmp.Diff([]string{"a", "b", "c"}, names()); diff != "" {
+		t.Errorf("order by another column mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResultContentSortBy_numbers(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		values []bigqueryapi.Value
+		want   []string
+	}{
+		"floats": {
+			values: []bigqueryapi.Value{math.Inf(1), 1.5, nil, math.NaN(), math.Inf(-1), -2.0},
+			want:   []string{"NULL", "NaN", "-Inf", "-2", "1.5", "+Inf"},
+		},
+		"ints and floats": {
+			values: []bigqueryapi.Value{int64(10), 2.5, int64(-1)},
+			want:   []string{"-1", "2.5", "10"},
+		},
+		"ints and numerics": {
+			values: []bigqueryapi.Value{int64(10), big.NewRat(5, 2), int64(-1)},
+			want:   []string{"-1", "2.5", "10"},
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rows := [][]bigqueryapi.Value{}
+			for _, v := range tc.values {
+				rows = append(rows, []bigqueryapi.Value{v})
+			}
+
+			c := &resultContent{sortColumn: -1}
+			c.SetResult(&bigquery.Result{Keys: []string{"n"}, Rows: rows})
+			c.SortBy(0)
+
+			got := []string{}
+			for i := range c.rows {
+				v, _ := c.Value(i, 0)
+				got = append(got, v)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("-want +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResultContentValue_duplicateColumns(t *testing.T) {
+	t.Parallel()
+
+	c := &resultContent{sortColumn: -1}
+	c.SetResult(&bigquery.Result{
+		Keys: []string{"id", "id"},
+		Rows: [][]bigqueryapi.Value{
+			{int64(1), int64(2)},
+		},
+	})
+
+	got := []string{}
+	for i := range c.keys {
+		v, _ := c.Value(0, i)
+		got = append(got, v)
+	}
+
+	if diff := cmp.Diff([]string{"1", "2"}, got); diff != "" {
+		t.Errorf("-want +got:\n%s", diff)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		a    bigqueryapi.Value
+		b    bigqueryapi.Value
+		want int
+	}{
+		"nil first": {
+			a:    nil,
+			b:    int64(1),
+			want: -1,
+		},
+		"ints": {
+			a:    int64(2),
+			b:    int64(10),
+			want: -1,
+		},
+		"floats": {
+			a:    float64(2.5),
+			b:    float64(10),
+			want: -1,
+		},
+		"nan before -inf": {
+			a:    math.NaN(),
+			b:    math.Inf(-1),
+			want: -1,
+		},
+		"nan equals nan": {
+			a:    math.NaN(),
+			b:    math.NaN(),
+			want: 0,
+		},
+		"+inf last": {
+			a:    math.Inf(1),
+			b:    math.MaxFloat64,
+			want: 1,
+		},
+		"nil before nan": {
+			a:    nil,
+			b:    math.NaN(),
+			want: -1,
+		},
+		"numeric": {
+			a:    big.NewRat(1, 3),
+			b:    big.NewRat(1, 2),
+			want: -1,
+		},
+		"strings": {
+			a:    "abc",
+			b:    "abc",
+			want: 0,
+		},
+		"bools": {
+			a:    false,
+			b:    true,
+			want: -1,
+		},
+		"timestamps": {
+			a:    time.Date(2023, 5, 3, 0, 0, 0, 0, time.UTC),
+			b:    time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC),
+			want: 1,
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := compareValues(tc.a, tc.b); got != tc.want {
+				t.Errorf("want: %d, got: %d", tc.want, got)
+			}
+		})
+	}
+}
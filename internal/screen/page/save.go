@@ -0,0 +1,218 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/googlesql"
+)
+
+const (
+	saveFormWidth  = 64
+	saveFormHeight = 7
+	saveTableWidth = 48
+
+	modalButtonSave = "Save"
+)
+
+var (
+	// writeDispositions are chosen by the labels in the same order
+	writeDispositions      = []string{bigquery.WriteEmpty, bigquery.WriteTruncate, bigquery.WriteAppend}
+	writeDispositionLabels = []string{"fail", "truncate", "append"}
+)
+
+// askDestination asks a destination table and what to do if it exists, and
+// runs the query in the editor to save its result into the table. The whole
+// text is run as Ctrl-X Enter does, so that a script keeps its variables and
+// temporary tables.
+func (q *Query) askDestination() {
+	query := q.textArea.GetText()
+	if strings.TrimSpace(query) == "" {
+		q.statusTextView.SetText("no query to save the result of").SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.isQueryRunning() {
+		q.statusTextView.SetText(messageAnotherQueryRunning).SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.modalFunc == nil {
+		return
+	}
+
+	name := ""
+	disposition := 0
+
+	if d := q.lastDestination; d != nil {
+		name = d.String()
+		disposition = max(slices.Index(writeDispositions, d.WriteDisposition), 0)
+	}
+
+	var closeModal func()
+
+	cancel := func() {
+		closeModal()
+		q.statusTextView.SetText("[CANCELLED] result was not saved").SetTextStyle(textStyleWarning)
+	}
+
+	form := tview.NewForm().
+		SetItemPadding(0).
+		AddInputField("table", name, saveTableWidth, nil, nil).
+		AddDropDown("if exists", writeDispositionLabels, disposition, nil)
+
+	form.
+		AddButton(modalButtonSave, func() {
+			table := form.GetFormItem(0).(*tview.InputField).GetText()
+			i, _ := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+
+			closeModal()
+
+			dst, err := bigquery.ParseDestination(table, q.projectID(), writeDispositions[i])
+			if err != nil {
+				q.statusTextView.SetText(err.Error()).SetTextStyle(textStyleError)
+				return
+			}
+
+			q.lastDestination = dst
+			q.saveQuery(query, dst)
+		}).
+		AddButton(modalButtonCancel, cancel).
+		SetCancelFunc(cancel)
+
+	form.SetBorder(true).SetTitle("save result into [project.]dataset.table")
+
+	closeModal = q.modalFunc(center(form, saveFormWidth, saveFormHeight))
+}
+
+// saveQuery saves the result of the query into dst, asking values of its
+// parameters first if any.
+func (q *Query) saveQuery(query string, dst *bigquery.Destination) {
+	names := googlesql.Parameters(query)
+	if len(names) == 0 {
+		q.saveResult(query, nil, dst)
+		return
+	}
+
+	q.askParameters(query, names, func(params []bigquery.Parameter) {
+		q.saveResult(query, params, dst)
+	})
+}
+
+// saveResult runs the query with dst as its destination in background, asking
+// confirmation first if it is expensive like a usual run. The run is recorded
+// in history, and the shown result is kept as is.
+func (q *Query) saveResult(query string, params []bigquery.Parameter, dst *bigquery.Destination) {
+	q.mu.Lock()
+	if q.cancelQuery != nil {
+		q.mu.Unlock()
+		q.statusTextView.SetText(messageAnotherQueryRunning).SetTextStyle(textStyleError)
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	q.cancelQuery = cancel
+	q.mu.Unlock()
+
+	q.statusTextView.SetText(fmt.Sprintf("saving result into %s...", dst)).SetTextStyle(textStyleDefault)
+
+	go func() {
+		defer func() {
+			q.mu.Lock()
+			q.cancelQuery = nil
+			q.mu.Unlock()
+
+			cancel()
+		}()
+
+		if q.opts.ConfirmBytes > 0 {
+			ok, err := q.confirmCost(ctx, query, params)
+			if err != nil {
+				q.handleSaveError(query, dst, err)
+
+				return
+			}
+
+			if !ok {
+				q.setStatusAsync("[CANCELLED] result was not saved", textStyleWarning)
+
+				return
+			}
+		}
+
+		start := time.Now()
+
+		r, err := q.bqClient.SaveQuery(ctx, query, dst, params...)
+		if err != nil {
+			q.handleSaveError(query, dst, err)
+
+			return
+		}
+
+		historyErr := q.history.Append(r)
+
+		q.app.QueueUpdateDraw(func() {
+			q.setStatusWithHistoryError(
+				fmt.Sprintf(
+					"[SAVED] %d row(s) written into %s, took %.2f seconds, processed %s of data",
+					r.RowsWritten,
+					r.Destination,
+					time.Since(start).Seconds(),
+					humanize.Bytes(uint64(r.TotalBytesProcessed)),
+				),
+				textStyleSuceess,
+				historyErr,
+			)
+		})
+	}()
+}
+
+// handleSaveError shows why the result is not saved. A save cancelled by the
+// user is recorded in history. This must be called outside of the
+// application's main loop.
+func (q *Query) handleSaveError(query string, dst *bigquery.Destination, err error) {
+	if errors.Is(err, context.Canceled) {
+		r := &bigquery.Result{
+			Query:       query,
+			Destination: dst.String(),
+			Cancelled:   true,
+			EndTime:     time.Now(),
+		}
+
+		historyErr := q.history.Append(r)
+
+		q.app.QueueUpdateDraw(func() {
+			q.setStatusWithHistoryError("[CANCELLED] result was not saved", textStyleWarning, historyErr)
+		})
+
+		return
+	}
+
+	msg := err.Error()
+
+	var qe *bigquery.QueryError
+	if errors.As(err, &qe) {
+		msg = qe.Message
+	}
+
+	q.setStatusAsync(fmt.Sprintf("[ERROR] cannot save result into %s: %s", dst, msg), textStyleError)
+}
+
+// projectID returns the project of the client, which is the default project of
+// destination tables.
+func (q *Query) projectID() string {
+	if cb, ok := q.bqClient.(bigquery.CatalogBackend); ok {
+		return cb.ProjectID()
+	}
+
+	return ""
+}
@@ -0,0 +1,39 @@
+package page
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	// schemaPanelWidth is the width of the schema side panel including borders
+	schemaPanelWidth = 48
+)
+
+// formatSchema lists fields of a result schema as columns of name, type, mode
+// and description. Nested fields are indented under their RECORD.
+func formatSchema(fields []*bigquery.Field) string {
+	if len(fields) == 0 {
+		return "no schema"
+	}
+
+	var b bytes.Buffer
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	writeSchemaFields(w, fields, 0)
+	w.Flush()
+
+	return b.String()
+}
+
+func writeSchemaFields(w *tabwriter.Writer, fields []*bigquery.Field, depth int) {
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", strings.Repeat("  ", depth), f.Name, f.Type, f.Mode, f.Description)
+
+		writeSchemaFields(w, f.Fields, depth+1)
+	}
+}
@@ -0,0 +1,233 @@
+package page
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	scriptListWidth  = 100
+	scriptListHeight = 20
+
+	// scriptQueryWidth is the width of statement texts shown in the list
+	scriptQueryWidth = 60
+)
+
+// setResult keeps r as the result shown in b. The result of a script is kept
+// as well, so that results of its statements can be shown in turn.
+func (b *buffer) setResult(r *bigquery.Result) {
+	b.lastResult = r
+	b.script = nil
+	b.fetchErr = nil
+
+	if numStatements(r) > 0 {
+		b.script = r
+	}
+}
+
+// numStatements returns the number of statements run by the script. Results
+// recorded in history before statements were read on demand have only
+// Children.
+func numStatements(r *bigquery.Result) int {
+	return max(int(r.NumChildJobs), len(r.Children))
+}
+
+// scriptStatus tells the number of statements run by the script for the
+// status bar.
+func scriptStatus(r *bigquery.Result) string {
+	n := numStatements(r)
+	if n == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(", ran %d statement(s), press Ctrl-X V to show each", n)
+}
+
+// selectStatementResult lets the user choose a statement of the last script
+// run, and shows its result. Results of the statements are read from BigQuery
+// when the list is opened first, which can be cancelled like a query.
+func (q *Query) selectStatementResult() {
+	b := q.current
+
+	if b.script == nil {
+		q.statusTextView.SetText("the last query is not a script").SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.modalFunc == nil {
+		return
+	}
+
+	script := b.script
+
+	if script.Children != nil {
+		q.showStatementList(b, script)
+		return
+	}
+
+	q.mu.Lock()
+	if q.cancelQuery != nil {
+		q.mu.Unlock()
+		q.statusTextView.SetText(messageAnotherQueryRunning).SetTextStyle(textStyleError)
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	q.cancelQuery = cancel
+	q.mu.Unlock()
+
+	q.statusTextView.SetText("reading results of statements...").SetTextStyle(textStyleDefault)
+
+	go func() {
+		defer func() {
+			q.mu.Lock()
+			q.cancelQuery = nil
+			q.mu.Unlock()
+
+			cancel()
+		}()
+
+		children, err := q.bqClient.ScriptChildren(ctx, script, q.firstPageSize())
+
+		q.app.QueueUpdateDraw(func() {
+			if err != nil {
+				if b != q.current {
+					return
+				}
+
+				if errors.Is(err, context.Canceled) {
+					q.statusTextView.SetText("[CANCELLED] results of statements were not read").SetTextStyle(textStyleWarning)
+				} else {
+					q.statusTextView.
+						SetText(fmt.Sprintf("[ERROR] cannot read results of statements: %s", err)).
+						SetTextStyle(textStyleError)
+				}
+
+				return
+			}
+
+			script.Children = children
+
+			// the user may have moved on while reading
+			if b != q.current || b.script != script {
+				return
+			}
+
+			q.statusTextView.SetText("").SetTextStyle(textStyleDefault)
+			q.showStatementList(b, script)
+		})
+	}()
+}
+
+// showStatementList shows the list of the statements run by the script.
+func (q *Query) showStatementList(b *buffer, script *bigquery.Result) {
+	var closeModal func()
+
+	list := tview.NewList().SetHighlightFullLine(true)
+
+	list.AddItem(
+		fmt.Sprintf("final result (%s)", statementType(script)),
+		"  "+describeStatementResult(script),
+		0,
+		func() {
+			closeModal()
+			q.showStatementResult(b, script, 0)
+		},
+	)
+
+	for i, c := range script.Children {
+		list.AddItem(
+			fmt.Sprintf("%d. %s: %s", i+1, statementType(c), summarizeQuery(c.Query)),
+			"  "+describeStatementResult(c),
+			0,
+			func() {
+				closeModal()
+				q.showStatementResult(b, c, i+1)
+			},
+		)
+
+		if c == b.lastResult {
+			list.SetCurrentItem(i + 1)
+		}
+	}
+
+	list.SetDoneFunc(func() {
+		closeModal()
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlG {
+			closeModal()
+			return nil
+		}
+
+		return event
+	})
+
+	list.SetBorder(true).SetTitle("script statements (Enter to show, Esc to close)")
+
+	closeModal = q.modalFunc(center(list, scriptListWidth, scriptListHeight))
+}
+
+// showStatementResult shows r, which is the result of the whole script if n is
+// 0, or of the n-th statement.
+func (q *Query) showStatementResult(b *buffer, r *bigquery.Result, n int) {
+	if b != q.current || b.script == nil {
+		return
+	}
+
+	b.lastResult = r
+	q.showResultTable(b, r)
+
+	label := "final result"
+	if n > 0 {
+		label = fmt.Sprintf("statement %d of %d", n, len(b.script.Children))
+	}
+
+	q.statusTextView.
+		SetText(fmt.Sprintf("[SCRIPT] %s, %s", label, describeStatementResult(r))).
+		SetTextStyle(textStyleDefault)
+}
+
+// describeStatementResult tells the statement type, rows and bytes processed.
+func describeStatementResult(r *bigquery.Result) string {
+	rows := fmt.Sprintf("%d row(s)", len(r.Rows))
+	if int64(len(r.Rows)) < r.TotalRows {
+		rows = fmt.Sprintf("%d of %d row(s) loaded", len(r.Rows), r.TotalRows)
+	}
+
+	return fmt.Sprintf("%s, %s, processed %s of data", statementType(r), rows, humanize.Bytes(uint64(r.TotalBytesProcessed)))
+}
+
+func statementType(r *bigquery.Result) string {
+	if r.StatementType == "" {
+		return "UNKNOWN"
+	}
+
+	return r.StatementType
+}
+
+// summarizeQuery returns the first line of the query, cut at
+// scriptQueryWidth.
+func summarizeQuery(query string) string {
+	s := strings.TrimSpace(query)
+
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + " ..."
+	}
+
+	if r := []rune(s); len(r) > scriptQueryWidth {
+		s = string(r[:scriptQueryWidth-3]) + "..."
+	}
+
+	return tview.Escape(s)
+}
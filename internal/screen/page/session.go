@@ -0,0 +1,150 @@
+package page
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/dtan4/bqc/internal/bigquery"
+)
+
+const (
+	sessionStatusWidth = 32
+	// sessionIDLength is the number of characters of the session ID shown in
+	// the status bar
+	sessionIDLength = 12
+
+	sessionRefreshInterval = 1 * time.Second
+
+	modalButtonEnd = "End"
+)
+
+// sessionBackend returns the client as a SessionBackend, or nil if queries do
+// not run in a session.
+func (q *Query) sessionBackend() bigquery.SessionBackend {
+	sb, ok := q.bqClient.(bigquery.SessionBackend)
+	if !ok || !sb.SessionEnabled() {
+		return nil
+	}
+
+	return sb
+}
+
+// startSessionTicker refreshes the session ID and age in the status bar until
+// the returned function is called.
+func (q *Query) startSessionTicker() (stop func()) {
+	ticker := time.NewTicker(sessionRefreshInterval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				q.app.QueueUpdateDraw(q.updateSessionStatus)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func (q *Query) updateSessionStatus() {
+	sb := q.sessionBackend()
+	if sb == nil {
+		return
+	}
+
+	s := sb.Session()
+	if s == nil {
+		q.sessionTextView.SetText("session: not started")
+		return
+	}
+
+	id := s.ID
+	if len(id) > sessionIDLength {
+		id = id[:sessionIDLength] + "…"
+	}
+
+	q.sessionTextView.SetText(fmt.Sprintf("session: %s (%s)", id, formatSessionAge(s.Age(time.Now()))))
+}
+
+// confirmEndSession asks the user whether to end the current session, whose
+// TEMP tables and variables are lost.
+func (q *Query) confirmEndSession() {
+	sb := q.sessionBackend()
+	if sb == nil {
+		q.statusTextView.SetText("session mode is disabled, run bqc with -session").SetTextStyle(textStyleError)
+		return
+	}
+
+	s := sb.Session()
+	if s == nil {
+		q.statusTextView.SetText(bigquery.ErrNoSession.Error()).SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.isQueryRunning() {
+		q.statusTextView.SetText(messageAnotherQueryRunning).SetTextStyle(textStyleError)
+		return
+	}
+
+	if q.modalFunc == nil {
+		return
+	}
+
+	var closeModal func()
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("End session %s?\nIts TEMP tables and variables are dropped.", s.ID)).
+		AddButtons([]string{modalButtonEnd, modalButtonCancel}).
+		SetDoneFunc(func(_ int, label string) {
+			closeModal()
+
+			if label == modalButtonEnd {
+				q.endSession(sb, s)
+			}
+		})
+
+	closeModal = q.modalFunc(modal)
+}
+
+// endSession ends the session in background. The next query starts a new one.
+func (q *Query) endSession(sb bigquery.SessionBackend, s *bigquery.Session) {
+	q.statusTextView.SetText("ending session...").SetTextStyle(textStyleDefault)
+
+	go func() {
+		err := sb.EndSession(q.ctx)
+
+		q.app.QueueUpdateDraw(func() {
+			q.updateSessionStatus()
+
+			if err != nil {
+				q.statusTextView.SetText(fmt.Sprintf("cannot end session: %s", err)).SetTextStyle(textStyleError)
+				return
+			}
+
+			q.statusTextView.
+				SetText(fmt.Sprintf("[SESSION] ended session %s, the next query starts a new one", s.ID)).
+				SetTextStyle(textStyleSuceess)
+		})
+	}()
+}
+
+// formatSessionAge formats d briefly, e.g. "42s", "5m07s" or "2h03m".
+func formatSessionAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
@@ -0,0 +1,39 @@
+package page
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatSessionAge(t *testing.T) {
+	t.Parallel()
+
+	testcases := map[string]struct {
+		d    time.Duration
+		want string
+	}{
+		"seconds": {
+			d:    42*time.Second + 300*time.Millisecond,
+			want: "42s",
+		},
+		"minutes": {
+			d:    5*time.Minute + 7*time.Second,
+			want: "5m07s",
+		},
+		"hours": {
+			d:    26*time.Hour + 3*time.Minute + 59*time.Second,
+			want: "26h03m",
+		},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatSessionAge(tc.d); got != tc.want {
+				t.Errorf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,9 +1,32 @@
 package page
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/dtan4/bqc/internal/googlesql"
+)
 
 var (
 	textStyleDefault = tcell.StyleDefault
 	textStyleSuceess = tcell.StyleDefault.Foreground(tcell.ColorGreenYellow)
 	textStyleError   = tcell.StyleDefault.Foreground(tcell.ColorRed)
+	textStyleWarning = tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	textStyleNull    = tcell.StyleDefault.Foreground(tcell.ColorGray)
+
+	lineBackgroundError = tcell.ColorMaroon
+
+	completionStyle         = tcell.StyleDefault.Background(tcell.ColorDarkSlateGray).Foreground(tcell.ColorWhite)
+	completionSelectedStyle = tcell.StyleDefault.Background(tcell.ColorTeal).Foreground(tcell.ColorWhite).Bold(true)
 )
+
+// syntaxStyles are styles of tokens in the editor. Tokens of other kinds are
+// drawn in textStyleDefault.
+var syntaxStyles = map[googlesql.TokenKind]tcell.Style{
+	googlesql.TokenKeyword:          tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Bold(true),
+	googlesql.TokenFunction:         tcell.StyleDefault.Foreground(tcell.ColorMediumPurple),
+	googlesql.TokenQuotedIdentifier: tcell.StyleDefault.Foreground(tcell.ColorDarkCyan),
+	googlesql.TokenString:           tcell.StyleDefault.Foreground(tcell.ColorDarkOrange),
+	googlesql.TokenNumber:           tcell.StyleDefault.Foreground(tcell.ColorDarkOrange),
+	googlesql.TokenParameter:        tcell.StyleDefault.Foreground(tcell.ColorGold),
+	googlesql.TokenComment:          tcell.StyleDefault.Foreground(tcell.ColorGray).Italic(true),
+}
@@ -7,51 +7,111 @@ import (
 	"github.com/rivo/tview"
 
 	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/catalog"
 	"github.com/dtan4/bqc/internal/checkpoint"
 	"github.com/dtan4/bqc/internal/history"
 	"github.com/dtan4/bqc/internal/screen/page"
 )
 
 const (
-	pageNameQuery = "query"
+	pageNameQuery   = "query"
+	pageNameHistory = "history"
+	pageNameCatalog = "catalog"
+	pageNameModal   = "modal"
 )
 
 type Screen struct {
 	app *tview.Application
+	ps  *tview.Pages
 
 	pages map[string]page.Page
 
-	bqClient   *bigquery.Client
-	checkpoint *checkpoint.Checkpoint
-	history    history.Storage
+	bqClient    bigquery.Backend
+	checkpoints *checkpoint.Dir
+	history     history.Storage
 }
 
 func New(
-	bqClient *bigquery.Client,
-	checkpoint *checkpoint.Checkpoint,
+	bqClient bigquery.Backend,
+	checkpoints *checkpoint.Dir,
 	history history.Storage,
+	catalog *catalog.Cache,
+	queryOpts page.QueryOptions,
 ) *Screen {
 	app := tview.NewApplication()
+	ps := tview.NewPages()
+
+	queryPage := page.NewQuery(app, bqClient, checkpoints, history, queryOpts)
+	historyPage := page.NewHistory(app, history)
+	catalogPage := page.NewCatalog(app, bqClient)
+
+	showModal := func(p tview.Primitive) func() {
+		ps.AddPage(pageNameModal, p, true, true)
+
+		return func() {
+			ps.RemovePage(pageNameModal)
+		}
+	}
+
+	queryPage.SetModalFunc(showModal)
+	queryPage.SetCatalog(catalog)
+
+	queryPage.SetOpenHistoryFunc(func() {
+		ps.SwitchToPage(pageNameHistory)
+		historyPage.Reload()
+	})
+
+	queryPage.SetOpenCatalogFunc(func() {
+		ps.SwitchToPage(pageNameCatalog)
+		catalogPage.Open()
+	})
+
+	historyPage.
+		SetLoadFunc(func(query string) {
+			queryPage.SetQuery(query)
+			ps.SwitchToPage(pageNameQuery)
+		}).
+		SetRenderFunc(func(r *bigquery.Result) {
+			queryPage.ShowResult(r)
+			ps.SwitchToPage(pageNameQuery)
+		}).
+		SetDoneFunc(func() {
+			ps.SwitchToPage(pageNameQuery)
+		})
+
+	catalogPage.
+		SetInsertFunc(func(name string) {
+			queryPage.InsertText(name)
+			ps.SwitchToPage(pageNameQuery)
+		}).
+		SetPreviewFunc(func(name string, r *bigquery.Result) {
+			queryPage.ShowPreview(name, r)
+			ps.SwitchToPage(pageNameQuery)
+		}).
+		SetDoneFunc(func() {
+			ps.SwitchToPage(pageNameQuery)
+		})
 
 	pages := map[string]page.Page{
-		pageNameQuery: page.NewQuery(app, bqClient, checkpoint, history),
+		pageNameQuery:   queryPage,
+		pageNameHistory: historyPage,
+		pageNameCatalog: catalogPage,
 	}
 
 	return &Screen{
-		app:        app,
-		pages:      pages,
-		bqClient:   bqClient,
-		checkpoint: checkpoint,
-		history:    history,
+		app:         app,
+		ps:          ps,
+		pages:       pages,
+		bqClient:    bqClient,
+		checkpoints: checkpoints,
+		history:     history,
 	}
 }
 
 func (s *Screen) Run(ctx context.Context) error {
-	ps := tview.NewPages()
-
 	for k, p := range s.pages {
-		p.Init()
-		ps.AddPage(k, p, true, false)
+		p.Init(ctx)
+		s.ps.AddPage(k, p, true, false)
 	}
 	defer func() {
 		for _, p := range s.pages {
@@ -59,9 +119,9 @@ func (s *Screen) Run(ctx context.Context) error {
 		}
 	}()
 
-	ps.SwitchToPage(pageNameQuery)
+	s.ps.SwitchToPage(pageNameQuery)
 
-	if err := s.app.SetRoot(ps, true).EnableMouse(true).Run(); err != nil {
+	if err := s.app.SetRoot(s.ps, true).EnableMouse(true).Run(); err != nil {
 		return fmt.Errorf("run TUI app: %w", err)
 	}
 
@@ -4,30 +4,48 @@ import (
 	"bufio"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/dustin/go-humanize"
 
 	"github.com/dtan4/bqc/internal/bigquery"
+	"github.com/dtan4/bqc/internal/catalog"
 	"github.com/dtan4/bqc/internal/checkpoint"
 	"github.com/dtan4/bqc/internal/history"
+	"github.com/dtan4/bqc/internal/profile"
+	"github.com/dtan4/bqc/internal/renderer"
 	"github.com/dtan4/bqc/internal/screen"
+	"github.com/dtan4/bqc/internal/screen/page"
 )
 
 const (
 	bigqueryConfigFilename = ".bigqueryrc"
 
 	historyBucket = "history"
+
+	firstBufferName = "query-1"
+
+	// emulatorHostEnv is the same variable as the one used by other BigQuery
+	// emulator clients
+	emulatorHostEnv = "BIGQUERY_EMULATOR_HOST"
 )
 
 var (
 	projectIDConfigRe = regexp.MustCompile(`^project_id = ([a-z0-9-]+)$`)
 
 	dataDir = filepath.Join(xdg.DataHome, "bqc")
+
+	profilesFilename = filepath.Join(xdg.ConfigHome, "bqc", "profiles")
 )
 
 func main() {
@@ -38,21 +56,115 @@ func main() {
 }
 
 func realMain(args []string) error {
+	var (
+		execQuery string
+		queryFile string
+		format    string
+		endpoint  string
+		maxRows   int
+
+		confirmBytes   string
+		maxBytesBilled string
+		pricePerTiB    float64
+		liveDryRun     time.Duration
+		session        bool
+		profileName    string
+		params         parameterFlags
+	)
+
+	fs := flag.NewFlagSet("bqc", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bqc [flags] [project ID]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&execQuery, "e", "", "run the given query non-interactively and print the result")
+	fs.StringVar(&queryFile, "f", "", "run the query in the given file non-interactively and print the result")
+	fs.Var(&params, "param", "query parameter of non-interactive mode as name:TYPE:value, e.g. day:DATE:2024-01-02 (repeatable)")
+	fs.StringVar(&format, "format", "table", "output format of non-interactive mode (table|markdown|tsv|csv|json|jsonl)")
+	fs.IntVar(&maxRows, "max-rows", 100000, "maximum number of result rows kept in memory in the TUI (0: no limit)")
+	fs.StringVar(&confirmBytes, "confirm-bytes", "100GB", "ask before running a query estimated to process more than this size in the TUI (0: never ask)")
+	fs.StringVar(&maxBytesBilled, "max-bytes-billed", humanize.Bytes(uint64(bigquery.DefaultMaxBytesBilled)), "make BigQuery fail queries billing more than this size, even if confirmed in the TUI (0: no limit)")
+	fs.Float64Var(&pricePerTiB, "price-per-tib", 6.25, "on-demand price per TiB in USD to estimate query cost")
+	fs.DurationVar(&liveDryRun, "live-dry-run", 0, "dry-run the statement at the cursor this long after typing stops in the TUI, e.g. 500ms (0: disabled)")
+	fs.BoolVar(&session, "session", false, "run queries in the TUI in a BigQuery session to keep TEMP tables and variables across queries (Ctrl-X A to end it)")
+	fs.StringVar(&profileName, "profile", profile.DefaultName, "profile of job settings in "+profilesFilename+" (location, dataset, labels, priority and legacy_sql)")
+	fs.StringVar(&endpoint, "endpoint", os.Getenv(emulatorHostEnv), "BigQuery API endpoint, e.g. a local emulator (env: "+emulatorHostEnv+")")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	prof, err := profile.Load(profilesFilename, profileName)
+	if err != nil {
+		return fmt.Errorf("load profile: %w", err)
+	}
+
 	var projectID string
 
-	if len(args) == 0 {
+	switch {
+	case fs.NArg() > 0:
+		projectID = fs.Arg(0)
+	case prof.ProjectID != "":
+		projectID = prof.ProjectID
+	default:
 		projectID = loadProjectIDFromConfig()
-	} else {
-		projectID = args[0]
 	}
 
 	if projectID == "" {
 		return errors.New("project ID must be provided")
 	}
 
-	ctx := context.Background()
+	confirmBytesN, err := humanize.ParseBytes(confirmBytes)
+	if err != nil {
+		return fmt.Errorf("parse -confirm-bytes: %w", err)
+	}
+
+	maxBytesBilledN, err := humanize.ParseBytes(maxBytesBilled)
+	if err != nil {
+		return fmt.Errorf("parse -max-bytes-billed: %w", err)
+	}
 
-	client, err := bigquery.NewClient(ctx, projectID)
+	query, batch, err := loadBatchQuery(execQuery, queryFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("load query: %w", err)
+	}
+
+	if len(params) > 0 && !batch {
+		return errors.New("-param is available only with a query given by -e, -f or stdin")
+	}
+
+	var rdr renderer.Renderer
+
+	if batch {
+		rdr, err = rendererFromFormat(format)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	clientOpts := []bigquery.ClientOption{
+		bigquery.WithMaxBytesBilled(int64(maxBytesBilledN)),
+		bigquery.WithLabels(jobLabels(prof.Labels)),
+	}
+
+	clientOpts = append(clientOpts, profileOptions(prof)...)
+
+	if session && !batch {
+		clientOpts = append(clientOpts, bigquery.WithSession())
+	}
+
+	if endpoint != "" {
+		clientOpts = append(clientOpts, bigquery.WithEndpoint(endpoint))
+	}
+
+	client, err := bigquery.NewClient(ctx, projectID, clientOpts...)
 	if err != nil {
 		return fmt.Errorf("create BigQuery client: %w", err)
 	}
@@ -62,15 +174,36 @@ func realMain(args []string) error {
 		return fmt.Errorf("create data dir: %s: %w", dataDir, err)
 	}
 
-	ckpt := checkpoint.New(filepath.Join(dataDir, "checkpoint"))
-
 	hs, err := history.NewLocalStorage(filepath.Join(dataDir, "history.db"), historyBucket)
 	if err != nil {
 		return fmt.Errorf("prepare local history storage: %w", err)
 	}
 	defer hs.Close()
 
-	scr := screen.New(client, ckpt, hs)
+	if batch {
+		return runBatch(ctx, client, hs, rdr, query, params, os.Stdout)
+	}
+
+	ckpts := checkpoint.NewDir(filepath.Join(dataDir, "buffers"))
+
+	// keep the query saved before buffers were introduced as the first one
+	if err := ckpts.Import(filepath.Join(dataDir, "checkpoint"), firstBufferName); err != nil {
+		return fmt.Errorf("import checkpoint: %w", err)
+	}
+
+	cc, err := catalog.NewCache(filepath.Join(dataDir, "catalog.db"), client)
+	if err != nil {
+		return fmt.Errorf("prepare catalog cache: %w", err)
+	}
+	defer cc.Close()
+
+	scr := screen.New(client, ckpts, hs, cc, page.QueryOptions{
+		MaxRows:         maxRows,
+		ConfirmBytes:    int64(confirmBytesN),
+		MaxBytesBilled:  int64(maxBytesBilledN),
+		PricePerTiB:     pricePerTiB,
+		LiveDryRunDelay: liveDryRun,
+	})
 
 	if err := scr.Run(ctx); err != nil {
 		return fmt.Errorf("run TUI app: %w", err)
@@ -79,6 +212,45 @@ func realMain(args []string) error {
 	return nil
 }
 
+// profileOptions configures jobs with the settings of the profile.
+func profileOptions(p *profile.Profile) []bigquery.ClientOption {
+	opts := []bigquery.ClientOption{}
+
+	if p.Location != "" {
+		opts = append(opts, bigquery.WithLocation(p.Location))
+	}
+
+	if p.DefaultDatasetID != "" {
+		opts = append(opts, bigquery.WithDefaultDataset(p.DefaultProjectID, p.DefaultDatasetID))
+	}
+
+	if p.Priority == profile.PriorityBatch {
+		opts = append(opts, bigquery.WithBatchPriority())
+	}
+
+	if p.LegacySQL {
+		opts = append(opts, bigquery.WithLegacySQL())
+	}
+
+	return opts
+}
+
+// jobLabels returns the labels attached to every job, tool=bqc and the OS user
+// name. Labels of the profile take precedence.
+func jobLabels(profileLabels map[string]string) map[string]string {
+	labels := map[string]string{
+		"tool": "bqc",
+	}
+
+	if u, err := user.Current(); err == nil {
+		labels["user"] = bigquery.LabelValue(u.Username)
+	}
+
+	maps.Copy(labels, profileLabels)
+
+	return labels
+}
+
 func loadProjectIDFromConfig() string {
 	home, err := os.UserHomeDir()
 	if err != nil {